@@ -0,0 +1,89 @@
+package gh
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v39/github"
+)
+
+// needsTagUpfront reports whether the tag of a release has to be created before the release.
+// Draft releases don't create their tag until they are published, and releases only ever
+// create lightweight tags.
+func needsTagUpfront() bool {
+	return draftReleases() || Globals.AnnotatedTags
+}
+
+// createTag creates the tag of a release at the given commit: an annotated tag object holding
+// the message when annotated tags are enabled, a lightweight tag otherwise
+func createTag(tag string, commitSHA string, message string) error {
+	target := commitSHA
+	if Globals.AnnotatedTags {
+		tagger, err := getTagger()
+		if err != nil {
+			return fmt.Errorf("error resolving the tagger: %w", err)
+		}
+		now := time.Now()
+		tagger.Date = &now
+		object, _, err := Globals.Client.Git.CreateTag(Globals.Ctx, Globals.Owner, Globals.Repo, &github.Tag{
+			Tag:     github.String(tag),
+			Message: github.String(message),
+			Tagger:  tagger,
+			Object:  &github.GitObject{Type: github.String("commit"), SHA: github.String(commitSHA)},
+		})
+		if err != nil {
+			return fmt.Errorf("error creating the tag object: %w", err)
+		}
+		target = object.GetSHA()
+	}
+	_, _, err := Globals.Client.Git.CreateRef(Globals.Ctx, Globals.Owner, Globals.Repo, &github.Reference{
+		Ref:    github.String("refs/tags/" + tag),
+		Object: &github.GitObject{SHA: github.String(target)},
+	})
+	return err
+}
+
+// getTagger returns the configured tagger, or the authenticated user
+func getTagger() (*github.CommitAuthor, error) {
+	if Globals.Tagger != "" {
+		return parseTagger(Globals.Tagger)
+	}
+	user, _, err := Globals.Client.Users.Get(Globals.Ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	name := user.GetName()
+	if name == "" {
+		name = user.GetLogin()
+	}
+	email := user.GetEmail()
+	if email == "" {
+		// users with a private email address
+		email = fmt.Sprintf("%d+%s@users.noreply.github.com", user.GetID(), user.GetLogin())
+	}
+	return &github.CommitAuthor{Name: github.String(name), Email: github.String(email)}, nil
+}
+
+// parseTagger reads a `Name <email>` identity
+func parseTagger(identity string) (*github.CommitAuthor, error) {
+	address, err := mail.ParseAddress(identity)
+	if err != nil || address.Name == "" {
+		return nil, fmt.Errorf("invalid tagger %q (expected \"Name <email>\")", identity)
+	}
+	return &github.CommitAuthor{Name: github.String(address.Name), Email: github.String(address.Address)}, nil
+}
+
+// tagMessage writes the message of an annotated tag: the new tag, where it comes from and the
+// release summary
+func tagMessage(oldTag string, newTag string, summary string) string {
+	message := newTag + "\n"
+	if oldTag != "" {
+		message += fmt.Sprintf("\n%s -> %s\n", oldTag, newTag)
+	}
+	if summary = strings.TrimSpace(summary); summary != "" {
+		message += "\n" + summary + "\n"
+	}
+	return message
+}
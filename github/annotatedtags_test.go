@@ -0,0 +1,51 @@
+package gh
+
+import "testing"
+
+func TestParseTagger(t *testing.T) {
+	testCases := []struct {
+		identity string
+		name     string
+		email    string
+		wantErr  bool
+	}{
+		{"autodeployer-bot <bot@example.com>", "autodeployer-bot", "bot@example.com", false},
+		{"\"Release Bot\" <release@example.com>", "Release Bot", "release@example.com", false},
+		{"bot@example.com", "", "", true},
+		{"autodeployer-bot", "", "", true},
+	}
+
+	for _, tc := range testCases {
+		tagger, err := parseTagger(tc.identity)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("parseTagger(%q): unexpected error %v", tc.identity, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if tagger.GetName() != tc.name || tagger.GetEmail() != tc.email {
+			t.Errorf("parseTagger(%q): expected %s <%s> but got %s <%s>", tc.identity, tc.name, tc.email, tagger.GetName(), tagger.GetEmail())
+		}
+	}
+}
+
+func TestTagMessage(t *testing.T) {
+	testCases := []struct {
+		oldTag   string
+		newTag   string
+		summary  string
+		expected string
+	}{
+		{"1.2.3", "1.2.4-rc1", "## What's changed since 1.2.3\n\n- fix: things\n", "1.2.4-rc1\n\n1.2.3 -> 1.2.4-rc1\n\n## What's changed since 1.2.3\n\n- fix: things\n"},
+		{"1.2.4-rc3", "1.2.4", "Promoted from 1.2.4-rc3 using autodeployer", "1.2.4\n\n1.2.4-rc3 -> 1.2.4\n\nPromoted from 1.2.4-rc3 using autodeployer\n"},
+		{"", "0.0.1-rc1", "", "0.0.1-rc1\n"},
+	}
+
+	for _, tc := range testCases {
+		actual := tagMessage(tc.oldTag, tc.newTag, tc.summary)
+		if actual != tc.expected {
+			t.Errorf("tagMessage(%q, %q): expected %q but got %q", tc.oldTag, tc.newTag, tc.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package gh
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v39/github"
+)
+
+const (
+	checksumsAssetName = "SHA256SUMS"
+	summaryAssetName   = "deployment-summary.json"
+	assetUploadRetries = 3
+)
+
+// deploymentSummary is the provenance of a release, uploaded as deployment-summary.json
+type deploymentSummary struct {
+	Repository      string            `json:"repository"`
+	Branch          string            `json:"branch"`
+	Commit          string            `json:"commit"`
+	Tag             string            `json:"tag"`
+	PreviousTag     string            `json:"previous_tag"`
+	Prerelease      bool              `json:"prerelease"`
+	Image           string            `json:"image,omitempty"`
+	DeploymentsRepo string            `json:"deployments_repo"`
+	Manifests       []string          `json:"manifests"`
+	CreatedAt       time.Time         `json:"created_at"`
+	Assets          map[string]string `json:"assets,omitempty"`
+}
+
+// expandAssetGlobs resolves the asset globs to files, keyed by the name they are uploaded as
+func expandAssetGlobs(patterns []string) (map[string]string, error) {
+	files := map[string]string{}
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid asset glob %q: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("asset glob %q matched no files", pattern)
+		}
+		for _, path := range matches {
+			info, err := os.Stat(path)
+			if err != nil {
+				return nil, err
+			}
+			if info.IsDir() {
+				continue
+			}
+			name := filepath.Base(path)
+			if existing, ok := files[name]; ok && existing != path {
+				return nil, fmt.Errorf("%s and %s would both be uploaded as %s", existing, path, name)
+			}
+			if name == checksumsAssetName || name == summaryAssetName {
+				return nil, fmt.Errorf("%s clashes with the generated %s asset", path, name)
+			}
+			files[name] = path
+		}
+	}
+	return files, nil
+}
+
+// hashFile returns the hex encoded SHA-256 of a file
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// renderChecksums lays out checksums (name -> sha256) the way `sha256sum` does
+func renderChecksums(checksums map[string]string) string {
+	names := make([]string, 0, len(checksums))
+	for name := range checksums {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var sums strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&sums, "%s  %s\n", checksums[name], name)
+	}
+	return sums.String()
+}
+
+// UploadReleaseAssets attaches the files matched by the asset globs, their SHA256SUMS and,
+// when enabled, a deployment summary to a release. Assets the release already has are skipped.
+func UploadReleaseAssets(release *github.RepositoryRelease, oldTag string) error {
+	if len(Globals.ReleaseAssets) == 0 && !Globals.ReleaseSummaryAsset {
+		return nil
+	}
+	files, err := expandAssetGlobs(Globals.ReleaseAssets)
+	if err != nil {
+		return err
+	}
+	checksums := map[string]string{}
+	for name, path := range files {
+		if checksums[name], err = hashFile(path); err != nil {
+			return err
+		}
+	}
+	tempDir, err := os.MkdirTemp("", "autodeployer-assets")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tempDir)
+	if len(files) > 0 {
+		files[checksumsAssetName] = filepath.Join(tempDir, checksumsAssetName)
+		if err := os.WriteFile(files[checksumsAssetName], []byte(renderChecksums(checksums)), 0o644); err != nil {
+			return err
+		}
+	}
+	if Globals.ReleaseSummaryAsset {
+		summary := deploymentSummary{
+			Repository:      Globals.Owner + "/" + Globals.Repo,
+			Branch:          Globals.Branch,
+			Commit:          release.GetTargetCommitish(),
+			Tag:             release.GetTagName(),
+			PreviousTag:     oldTag,
+			Prerelease:      release.GetPrerelease(),
+			DeploymentsRepo: Globals.DeploymentsRepo,
+			CreatedAt:       time.Now().UTC(),
+			Assets:          checksums,
+		}
+		for _, manifest := range Globals.StagingManifests {
+			summary.Manifests = append(summary.Manifests, manifest.Path)
+		}
+		if Globals.ConfigImageURL != "" {
+			summary.Image = Globals.ConfigImageURL + ":" + imageTag(release.GetTagName())
+		}
+		content, err := json.MarshalIndent(summary, "", "  ")
+		if err != nil {
+			return err
+		}
+		files[summaryAssetName] = filepath.Join(tempDir, summaryAssetName)
+		if err := os.WriteFile(files[summaryAssetName], append(content, '\n'), 0o644); err != nil {
+			return err
+		}
+	}
+
+	existing := map[string]bool{}
+	options := &github.ListOptions{PerPage: 100}
+	for {
+		assets, resp, err := Globals.Client.Repositories.ListReleaseAssets(Globals.Ctx, Globals.Owner, Globals.Repo, release.GetID(), options)
+		if err != nil {
+			return fmt.Errorf("error listing the assets of %s: %w", release.GetTagName(), err)
+		}
+		for _, asset := range assets {
+			existing[asset.GetName()] = true
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		options.Page = resp.NextPage
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if existing[name] {
+			fmt.Printf("Asset %s is already attached to %s, skipping it.\n", name, release.GetTagName())
+			continue
+		}
+		if err := uploadReleaseAsset(release.GetID(), name, files[name]); err != nil {
+			return fmt.Errorf("error uploading %s: %w", name, err)
+		}
+		fmt.Printf("Uploaded asset %s.\n", name)
+	}
+	return nil
+}
+
+// uploadReleaseAsset uploads a file, retrying failed attempts. An asset that turns out to
+// already exist (e.g. uploaded by an attempt whose response got lost) counts as uploaded.
+func uploadReleaseAsset(releaseID int64, name string, path string) error {
+	var err error
+	for attempt := 1; attempt <= assetUploadRetries; attempt++ {
+		var file *os.File
+		file, err = os.Open(path)
+		if err != nil {
+			return err
+		}
+		_, _, err = Globals.Client.Repositories.UploadReleaseAsset(Globals.Ctx, Globals.Owner, Globals.Repo, releaseID, &github.UploadOptions{Name: name}, file)
+		file.Close()
+		if err == nil || isAlreadyExists(err) {
+			return nil
+		}
+		if attempt < assetUploadRetries {
+			fmt.Printf("Attempt %d to upload %s failed: %s\nRetrying...\n", attempt, name, err)
+			time.Sleep(time.Duration(attempt*2) * time.Second)
+		}
+	}
+	return err
+}
+
+// isAlreadyExists reports whether an API error is the 422 of a duplicate asset
+func isAlreadyExists(err error) bool {
+	var errorResponse *github.ErrorResponse
+	if !errors.As(err, &errorResponse) || errorResponse.Response == nil || errorResponse.Response.StatusCode != http.StatusUnprocessableEntity {
+		return false
+	}
+	for _, e := range errorResponse.Errors {
+		if e.Code == "already_exists" {
+			return true
+		}
+	}
+	return false
+}
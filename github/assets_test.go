@@ -0,0 +1,72 @@
+package gh
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExpandAssetGlobs(t *testing.T) {
+	dir := t.TempDir()
+	for _, path := range []string{"dist/app.tar.gz", "dist/app.zip", "dist/nested/app.tar.gz", "other/SHA256SUMS", "sbom.json"} {
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(path), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testCases := []struct {
+		patterns []string
+		expected map[string]string
+		wantErr  bool
+	}{
+		{[]string{"dist/*", "sbom.json"}, map[string]string{"app.tar.gz": "dist/app.tar.gz", "app.zip": "dist/app.zip", "sbom.json": "sbom.json"}, false},
+		{[]string{"dist/*.zip", "dist/*.zip"}, map[string]string{"app.zip": "dist/app.zip"}, false},
+		{[]string{"dist/*.tar.gz", "dist/nested/*"}, nil, true},
+		{[]string{"other/*"}, nil, true},
+		{[]string{"missing/*"}, nil, true},
+		{[]string{"dist/["}, nil, true},
+	}
+
+	for _, tc := range testCases {
+		var patterns []string
+		for _, pattern := range tc.patterns {
+			patterns = append(patterns, filepath.Join(dir, pattern))
+		}
+		actual, err := expandAssetGlobs(patterns)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("expandAssetGlobs(%v): unexpected error %v", tc.patterns, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		expected := map[string]string{}
+		for name, path := range tc.expected {
+			expected[name] = filepath.Join(dir, path)
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("expandAssetGlobs(%v): expected %v but got %v", tc.patterns, expected, actual)
+		}
+	}
+}
+
+func TestRenderChecksums(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.tar.gz")
+	if err := os.WriteFile(path, []byte("hello\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	hash, err := hashFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual := renderChecksums(map[string]string{"zz.zip": "00ff", "app.tar.gz": hash})
+	expected := "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03  app.tar.gz\n00ff  zz.zip\n"
+	if actual != expected {
+		t.Errorf("renderChecksums: expected %q but got %q", expected, actual)
+	}
+}
@@ -0,0 +1,200 @@
+package gh
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+)
+
+const (
+	// entries not used for this long are removed
+	cacheMaxAge = 30 * 24 * time.Hour
+	// the least recently used entries are removed above this size
+	cacheMaxBytes = 100 << 20
+)
+
+// the tag, release and commit listings (and single commits, which never change) are cached.
+// Anything else, e.g. polled workflow runs or file contents, always goes to GitHub.
+var cachedEndpoint = regexp.MustCompile(`/repos/[^/]+/[^/]+/(tags|releases|commits|commits/[0-9a-f]{40})$`)
+
+// CachingTransport stores the GET responses of tag, release and commit listings on disk and
+// revalidates them with If-None-Match. GitHub doesn't count `304 Not Modified` responses
+// against the rate limit, so repeated runs over unchanged tags and releases are almost free.
+type CachingTransport struct {
+	Dir  string
+	Base http.RoundTripper
+}
+
+type cachedResponse struct {
+	ETag   string      `json:"etag"`
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+}
+
+// NewCachingTransport wraps base with an on-disk cache in dir
+func NewCachingTransport(dir string, base http.RoundTripper) (*CachingTransport, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("error creating cache directory: %w", err)
+	}
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	transport := &CachingTransport{Dir: dir, Base: base}
+	if err := transport.evict(time.Now()); err != nil {
+		fmt.Printf("Failed to clean up the response cache: %s\n", err)
+	}
+	return transport, nil
+}
+
+// evict removes the entries unused for cacheMaxAge, then the least recently used ones until
+// the cache fits in cacheMaxBytes
+func (t *CachingTransport) evict(now time.Time) error {
+	entries, err := os.ReadDir(t.Dir)
+	if err != nil {
+		return err
+	}
+	var files []os.FileInfo
+	var size int64
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || info.IsDir() {
+			continue
+		}
+		if now.Sub(info.ModTime()) > cacheMaxAge {
+			os.Remove(filepath.Join(t.Dir, info.Name()))
+			continue
+		}
+		files = append(files, info)
+		size += info.Size()
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].ModTime().Before(files[j].ModTime()) })
+	for _, file := range files {
+		if size <= cacheMaxBytes {
+			break
+		}
+		if err := os.Remove(filepath.Join(t.Dir, file.Name())); err != nil {
+			return err
+		}
+		size -= file.Size()
+	}
+	return nil
+}
+
+// DefaultCacheDir returns the directory used when none is configured
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "autodeployer"), nil
+}
+
+func (t *CachingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || req.Header.Get("Range") != "" || !cachedEndpoint.MatchString(req.URL.Path) {
+		return t.Base.RoundTrip(req)
+	}
+	path := t.path(req)
+	cached, _ := t.load(path)
+	if cached != nil {
+		req = req.Clone(req.Context())
+		req.Header.Set("If-None-Match", cached.ETag)
+	}
+
+	resp, err := t.Base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		resp.Body.Close()
+		return cached.response(req, resp), nil
+	}
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("ETag") == "" {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	entry := &cachedResponse{ETag: resp.Header.Get("ETag"), Header: resp.Header, Body: body}
+	if err := t.store(path, entry); err != nil {
+		fmt.Printf("Failed to cache response for %s: %s\n", req.URL, err)
+	}
+	return resp, nil
+}
+
+// path derives the cache file from everything that can change the response body
+func (t *CachingTransport) path(req *http.Request) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\n%s\n%s", req.URL.String(), req.Header.Get("Accept"), req.Header.Get("Authorization"))
+	return filepath.Join(t.Dir, hex.EncodeToString(hash.Sum(nil))+".json")
+}
+
+func (t *CachingTransport) load(path string) (*cachedResponse, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entry cachedResponse
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+	// the modification time tracks the last use of the entry for eviction
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return &entry, nil
+}
+
+func (t *CachingTransport) store(path string, entry *cachedResponse) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	// write to a temporary file first so that concurrent runs never read a partial entry
+	tmp, err := os.CreateTemp(t.Dir, "tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// response rebuilds a 200 response from the cache, keeping the fresh rate limit headers of
+// the 304 so that go-github reports the real remaining quota
+func (c *cachedResponse) response(req *http.Request, notModified *http.Response) *http.Response {
+	header := c.Header.Clone()
+	for key, values := range notModified.Header {
+		header[key] = values
+	}
+	header.Del("Content-Length")
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         notModified.Proto,
+		ProtoMajor:    notModified.ProtoMajor,
+		ProtoMinor:    notModified.ProtoMinor,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(c.Body)),
+		ContentLength: int64(len(c.Body)),
+		Request:       req,
+	}
+}
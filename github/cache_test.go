@@ -0,0 +1,119 @@
+package gh
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCachingTransport(t *testing.T) {
+	requests, notModified := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified++
+			w.Header().Set("X-RateLimit-Remaining", "4999")
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("X-RateLimit-Remaining", "4998")
+		io.WriteString(w, `[{"name":"1.2.3"}]`)
+	}))
+	defer server.Close()
+
+	transport, err := NewCachingTransport(t.TempDir(), nil)
+	if err != nil {
+		t.Fatalf("Error returned from NewCachingTransport: %v", err)
+	}
+	client := &http.Client{Transport: transport}
+
+	for i := 0; i < 3; i++ {
+		resp, err := client.Get(server.URL + "/repos/o/r/tags?page=2")
+		if err != nil {
+			t.Fatalf("Error returned from Get: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || string(body) != `[{"name":"1.2.3"}]` {
+			t.Errorf("Request %d: unexpected response %d %s", i, resp.StatusCode, body)
+		}
+		if i > 0 && resp.Header.Get("X-RateLimit-Remaining") != "4999" {
+			t.Errorf("Request %d: expected the rate limit headers of the 304 but got %s", i, resp.Header.Get("X-RateLimit-Remaining"))
+		}
+	}
+	if requests != 3 || notModified != 2 {
+		t.Errorf("Expected 3 requests with 2 revalidations but got %d and %d", requests, notModified)
+	}
+}
+
+func TestCachingTransportEndpoints(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") != "" {
+			t.Errorf("%s should not be revalidated from the cache", r.URL.Path)
+		}
+		w.Header().Set("ETag", `"v1"`)
+		io.WriteString(w, `{}`)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	transport, err := NewCachingTransport(dir, nil)
+	if err != nil {
+		t.Fatalf("Error returned from NewCachingTransport: %v", err)
+	}
+	client := &http.Client{Transport: transport}
+	for _, path := range []string{"/repos/o/r/actions/runs", "/repos/o/r/contents/staging.yaml", "/repos/o/r/releases/1"} {
+		for i := 0; i < 2; i++ {
+			resp, err := client.Get(server.URL + path)
+			if err != nil {
+				t.Fatalf("Error returned from Get: %v", err)
+			}
+			resp.Body.Close()
+		}
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Expected nothing to be cached but found %d entries", len(entries))
+	}
+}
+
+func TestCachingTransportEvict(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	for name, age := range map[string]time.Duration{"fresh.json": time.Hour, "stale.json": cacheMaxAge + time.Hour} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("{}"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, now.Add(-age), now.Add(-age)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// commits never change, their cache is kept however old it is
+	cache := loadCommitCache(dir)
+	cache.set("a", commitInfo{Author: "alice"})
+	if err := cache.save(); err != nil {
+		t.Fatal(err)
+	}
+	stale := now.Add(-cacheMaxAge - time.Hour)
+	if err := os.Chtimes(cache.path, stale, stale); err != nil {
+		t.Fatal(err)
+	}
+	transport := &CachingTransport{Dir: dir}
+	if err := transport.evict(now); err != nil {
+		t.Fatalf("Error returned from evict: %v", err)
+	}
+	if info, ok := loadCommitCache(dir).get("a"); !ok || info.Author != "alice" {
+		t.Errorf("Expected the commit cache to be kept but got %+v", info)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "fresh.json")); err != nil {
+		t.Errorf("Expected the fresh entry to be kept: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "stale.json")); !os.IsNotExist(err) {
+		t.Errorf("Expected the stale entry to be evicted")
+	}
+}
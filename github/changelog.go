@@ -0,0 +1,321 @@
+package gh
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v39/github"
+)
+
+const changelogHeader = `# Changelog
+
+All notable changes to this project will be documented in this file.
+
+The format is based on [Keep a Changelog](https://keepachangelog.com/en/1.1.0/).
+`
+
+// the Keep a Changelog categories, in the order they are rendered
+var changelogCategories = []string{"Added", "Changed", "Deprecated", "Removed", "Fixed", "Security"}
+
+// conventional commit types that don't belong in a changelog
+var changelogIgnoredTypes = map[string]bool{"chore": true, "docs": true, "ci": true, "test": true, "style": true, "build": true}
+
+// the messages of the changelog commits autodeployer makes in the source repo
+const (
+	changelogRCCommitMessage      = "chore(changelog): add changes of %s using autodeployer"
+	changelogReleaseCommitMessage = "chore(changelog): release %s using autodeployer"
+)
+
+// isAutodeployerCommit reports whether a commit message is one of the changelog commits made
+// by autodeployer, for a tag or a comma separated list of tags
+func isAutodeployerCommit(message string) bool {
+	message = strings.TrimSpace(message)
+	for _, format := range []string{changelogRCCommitMessage, changelogReleaseCommitMessage} {
+		prefix, suffix, _ := strings.Cut(format, "%s")
+		tag, ok := strings.CutPrefix(message, prefix)
+		if !ok {
+			continue
+		}
+		tag, ok = strings.CutSuffix(tag, suffix)
+		if ok && tag != "" && !strings.ContainsAny(tag, " \n") {
+			return true
+		}
+	}
+	return false
+}
+
+// changelog is a Keep a Changelog document split around its Unreleased section
+type changelog struct {
+	preamble   string
+	unreleased map[string][]string
+	// the categories of the Unreleased section in the order they appeared
+	order []string
+	rest  string
+}
+
+func parseChangelog(content string) *changelog {
+	doc := &changelog{unreleased: map[string][]string{}}
+	if strings.TrimSpace(content) == "" {
+		doc.preamble = changelogHeader
+		return doc
+	}
+	lines := strings.SplitAfter(content, "\n")
+	i := 0
+	for i < len(lines) && !strings.HasPrefix(lines[i], "## ") {
+		doc.preamble += lines[i]
+		i++
+	}
+	if i < len(lines) && strings.HasPrefix(strings.ToLower(strings.TrimSpace(lines[i])), "## [unreleased]") {
+		category := ""
+		for i++; i < len(lines) && !strings.HasPrefix(lines[i], "## "); i++ {
+			line := strings.TrimRight(lines[i], "\r\n")
+			switch {
+			case strings.HasPrefix(line, "### "):
+				category = strings.TrimSpace(strings.TrimPrefix(line, "### "))
+			case strings.HasPrefix(line, "- "):
+				doc.add(category, line)
+			case strings.TrimSpace(line) != "" && len(doc.unreleased[category]) > 0:
+				// continuation of the previous entry
+				entries := doc.unreleased[category]
+				entries[len(entries)-1] += "\n" + line
+			}
+		}
+	}
+	doc.rest = strings.Join(lines[i:], "")
+	return doc
+}
+
+func (c *changelog) add(category string, entry string) {
+	if _, ok := c.unreleased[category]; !ok {
+		c.order = append(c.order, category)
+	}
+	for _, existing := range c.unreleased[category] {
+		if existing == entry {
+			return
+		}
+	}
+	c.unreleased[category] = append(c.unreleased[category], entry)
+}
+
+// renderSection lays out the categories of the Unreleased section under the given heading
+func (c *changelog) renderSection(heading string) string {
+	var section strings.Builder
+	section.WriteString(heading + "\n")
+	categories := append([]string{}, changelogCategories...)
+	for _, category := range c.order {
+		if !containsString(categories, category) {
+			categories = append(categories, category)
+		}
+	}
+	for _, category := range categories {
+		if len(c.unreleased[category]) == 0 {
+			continue
+		}
+		if category != "" {
+			section.WriteString("\n### " + category + "\n")
+		}
+		section.WriteString("\n" + strings.Join(c.unreleased[category], "\n") + "\n")
+	}
+	return section.String()
+}
+
+func (c *changelog) render(released string) string {
+	document := c.renderSection("## [Unreleased]")
+	if preamble := strings.TrimRight(c.preamble, "\n"); preamble != "" {
+		document = preamble + "\n\n" + document
+	}
+	if released != "" {
+		document += "\n" + released
+	}
+	if rest := strings.TrimLeft(c.rest, "\n"); rest != "" {
+		document += "\n" + rest
+	}
+	return document
+}
+
+// changelogCategory maps an entry to its Keep a Changelog category, or "" when it's left out
+func changelogCategory(entry releaseNoteEntry) string {
+	commitType, breaking := parseConventionalCommit(entry.Title + "\n\n" + entry.Body)
+	switch {
+	case breaking:
+		return "Changed"
+	case commitType == "feat":
+		return "Added"
+	case commitType == "fix":
+		return "Fixed"
+	case commitType == "revert":
+		return "Removed"
+	case changelogIgnoredTypes[commitType]:
+		return ""
+	}
+	return "Changed"
+}
+
+// addUnreleasedEntries accumulates the entries of a release candidate in the Unreleased section
+func addUnreleasedEntries(content string, entries []releaseNoteEntry) string {
+	doc := parseChangelog(content)
+	for _, entry := range entries {
+		category := changelogCategory(entry)
+		if category == "" {
+			continue
+		}
+		line := "- " + entry.Title
+		if _, breaking := parseConventionalCommit(entry.Title + "\n\n" + entry.Body); breaking && !strings.Contains(entry.Title, "!") {
+			line = "- **Breaking:** " + entry.Title
+		}
+		if entry.PR != 0 {
+			line += fmt.Sprintf(" (#%d)", entry.PR)
+		}
+		if entry.Author != "" {
+			line += " by @" + entry.Author
+		}
+		doc.add(category, line)
+	}
+	return doc.render("")
+}
+
+// releaseUnreleasedEntries moves the entries of the Unreleased section of the released commit
+// (rcContent) under the heading of a final release in the changelog of the branch (content).
+// Entries added to the branch by later rcs stay in its Unreleased section.
+func releaseUnreleasedEntries(content string, rcContent string, version string, date time.Time) string {
+	doc, rcDoc := parseChangelog(content), parseChangelog(rcContent)
+	released := rcDoc.renderSection(fmt.Sprintf("## [%s] - %s", version, date.Format("2006-01-02")))
+	if len(rcDoc.order) == 0 {
+		released += "\n- No notable changes\n"
+	}
+	doc.remove(rcDoc)
+	return doc.render(released)
+}
+
+// remove drops the Unreleased entries of another changelog from this one
+func (c *changelog) remove(other *changelog) {
+	var order []string
+	for _, category := range c.order {
+		var kept []string
+		for _, entry := range c.unreleased[category] {
+			if !containsString(other.unreleased[category], entry) {
+				kept = append(kept, entry)
+			}
+		}
+		if len(kept) == 0 {
+			delete(c.unreleased, category)
+			continue
+		}
+		c.unreleased[category] = kept
+		order = append(order, category)
+	}
+	c.order = order
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// the changelog updates of the rcs of the run, committed together by CommitChangelogs
+var (
+	changelogUpdates = map[string]string{}
+	changelogTags    []string
+)
+
+// UpdateUnreleasedChangelog adds the changes of a release candidate to the Unreleased section
+// of the changelog, before the rc release is cut, when rc changelog commits are enabled. The
+// update is only committed by CommitChangelogs, along with the updates of the other components
+// of the run.
+func UpdateUnreleasedChangelog(oldTag string, newTag string) {
+	if Globals.ChangelogPath == "" || !Globals.ChangelogRCCommit {
+		return
+	}
+	fmt.Printf("Adding the changes of %s to %s...\n", newTag, Globals.ChangelogPath)
+	entries, err := getReleaseNoteEntries(oldTag)
+	if err != nil {
+		fmt.Printf("Error when collecting changelog entries: %s\nWill not update %s.\n", err, Globals.ChangelogPath)
+		return
+	}
+	// components sharing a changelog add to the same update
+	content, ok := changelogUpdates[Globals.ChangelogPath]
+	if !ok {
+		_, existing, err := getFileContent(Globals.Repo, Globals.ChangelogPath, headRef())
+		if err == nil {
+			content = existing
+		} else if !isNotFound(err) {
+			fmt.Printf("Failed to read %s: %s\nWill not update it.\n", Globals.ChangelogPath, err)
+			return
+		}
+	}
+	newContent := addUnreleasedEntries(content, entries)
+	if newContent == content {
+		fmt.Printf("%s is already up to date.\n", Globals.ChangelogPath)
+		return
+	}
+	changelogUpdates[Globals.ChangelogPath] = newContent
+	changelogTags = append(changelogTags, newTag)
+}
+
+// CommitChangelogs commits the changelog updates of the run in a single commit on top of the
+// resolved head. It only fast-forwards the branch and becomes the new resolved head, so every
+// rc of the run is released from it. When the commit can't be made, the rcs are released from
+// the resolved head without it.
+func CommitChangelogs() {
+	if len(changelogUpdates) == 0 {
+		return
+	}
+	defer func() { changelogUpdates, changelogTags = map[string]string{}, nil }()
+	commitSHA, err := createCommit(Globals.Repo, headRef(), changelogUpdates, fmt.Sprintf(changelogRCCommitMessage, strings.Join(changelogTags, ",")))
+	if err != nil {
+		fmt.Printf("Failed to update the changelog: %s\nWill release %.7s without it.\n", err, headRef())
+		return
+	}
+	// not forced, so this fails when someone pushed to the branch since the run started
+	_, _, err = Globals.Client.Git.UpdateRef(Globals.Ctx, Globals.Owner, Globals.Repo, &github.Reference{
+		Ref:    github.String("refs/heads/" + Globals.Branch),
+		Object: &github.GitObject{SHA: github.String(commitSHA)},
+	}, false)
+	if err != nil {
+		fmt.Printf("Failed to push the changelog update to %s (did the branch move since the run started?): %s\nWill release %.7s without it.\n", Globals.Branch, err, headRef())
+		return
+	}
+	Globals.HeadSHA = commitSHA
+	fmt.Printf("Successfully updated the changelog! %s moved to %.7s, pull before pushing to it again.\n", Globals.Branch, commitSHA)
+}
+
+// releaseChangelog lands the changelog section of a final release on the branch of the rc. The
+// release itself stays at the rc commit, so the released code is exactly what was tested as the
+// rc. The commit only fast-forwards the branch and fails when it moved in the meantime.
+func releaseChangelog(branch string, rcCommitSHA string, finalTag string) error {
+	fmt.Printf("Releasing the changes of %s in %s on %s...\n", finalTag, Globals.ChangelogPath, branch)
+	branchRef := "refs/heads/" + branch
+	ref, _, err := Globals.Client.Git.GetRef(Globals.Ctx, Globals.Owner, Globals.Repo, branchRef)
+	if err != nil {
+		return fmt.Errorf("error fetching %s: %w", branch, err)
+	}
+	headSHA := ref.GetObject().GetSHA()
+	contents := map[string]string{}
+	for _, sha := range []string{rcCommitSHA, headSHA} {
+		_, existing, err := getFileContent(Globals.Repo, Globals.ChangelogPath, sha)
+		if err != nil && !isNotFound(err) {
+			return fmt.Errorf("error reading %s at %.7s: %w", Globals.ChangelogPath, sha, err)
+		}
+		contents[sha] = existing
+	}
+	newContent := releaseUnreleasedEntries(contents[headSHA], contents[rcCommitSHA], finalTag, time.Now())
+	commitSHA, err := createCommit(Globals.Repo, headSHA, map[string]string{Globals.ChangelogPath: newContent}, fmt.Sprintf(changelogReleaseCommitMessage, finalTag))
+	if err != nil {
+		return fmt.Errorf("error committing %s: %w", Globals.ChangelogPath, err)
+	}
+	// not forced, so this fails when someone pushed to the branch since it was read
+	_, _, err = Globals.Client.Git.UpdateRef(Globals.Ctx, Globals.Owner, Globals.Repo, &github.Reference{
+		Ref:    github.String(branchRef),
+		Object: &github.GitObject{SHA: github.String(commitSHA)},
+	}, false)
+	if err != nil {
+		return fmt.Errorf("error pushing the %s update to %s (did the branch move?): %w", Globals.ChangelogPath, branch, err)
+	}
+	fmt.Printf("Successfully updated %s!\n", Globals.ChangelogPath)
+	return nil
+}
@@ -0,0 +1,138 @@
+package gh
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAddUnreleasedEntries(t *testing.T) {
+	existing := `# Changelog
+
+## [Unreleased]
+
+### Fixed
+
+- fix: earlier fix (#3) by @bob
+
+## [1.2.3] - 2024-09-01
+
+### Added
+
+- feat: first feature
+`
+	entries := []releaseNoteEntry{
+		{Title: "feat: add search", Author: "alice", PR: 12},
+		{Title: "fix: earlier fix", Author: "bob", PR: 3},
+		{Title: "chore: bump deps", Author: "alice", SHA: "abc"},
+		{Title: "Rework the cart", Body: "BREAKING CHANGE: carts are per user", Author: "carol"},
+	}
+	expected := `# Changelog
+
+## [Unreleased]
+
+### Added
+
+- feat: add search (#12) by @alice
+
+### Changed
+
+- **Breaking:** Rework the cart by @carol
+
+### Fixed
+
+- fix: earlier fix (#3) by @bob
+
+## [1.2.3] - 2024-09-01
+
+### Added
+
+- feat: first feature
+`
+	if actual := addUnreleasedEntries(existing, entries); actual != expected {
+		t.Errorf("Expected:\n%s\nbut got:\n%s", expected, actual)
+	}
+}
+
+func TestAddUnreleasedEntriesToNewChangelog(t *testing.T) {
+	expected := changelogHeader + `
+## [Unreleased]
+
+### Fixed
+
+- fix: crash on start by @bob
+`
+	if actual := addUnreleasedEntries("", []releaseNoteEntry{{Title: "fix: crash on start", Author: "bob"}}); actual != expected {
+		t.Errorf("Expected:\n%s\nbut got:\n%s", expected, actual)
+	}
+}
+
+func TestReleaseUnreleasedEntries(t *testing.T) {
+	date := time.Date(2024, time.October, 18, 0, 0, 0, 0, time.UTC)
+	existing := `# Changelog
+
+## [Unreleased]
+
+### Fixed
+
+- fix: a bug
+  spanning two lines
+
+## [1.2.3] - 2024-09-01
+
+- older entry
+`
+	expected := `# Changelog
+
+## [Unreleased]
+
+## [1.2.4] - 2024-10-18
+
+### Fixed
+
+- fix: a bug
+  spanning two lines
+
+## [1.2.3] - 2024-09-01
+
+- older entry
+`
+	if actual := releaseUnreleasedEntries(existing, existing, "1.2.4", date); actual != expected {
+		t.Errorf("Expected:\n%s\nbut got:\n%s", expected, actual)
+	}
+
+	expected = `## [Unreleased]
+
+## [1.2.5] - 2024-10-18
+
+- No notable changes
+
+## [1.2.4] - 2024-10-01
+`
+	if actual := releaseUnreleasedEntries("## [1.2.4] - 2024-10-01\n", "## [1.2.4] - 2024-10-01\n", "1.2.5", date); actual != expected {
+		t.Errorf("Expected:\n%s\nbut got:\n%s", expected, actual)
+	}
+
+	// the branch moved on with the changes of a later rc, which stay unreleased
+	branch := "## [Unreleased]\n\n### Added\n\n- feat: later\n\n### Fixed\n\n- fix: released\n"
+	rc := "## [Unreleased]\n\n### Fixed\n\n- fix: released\n"
+	expected = "## [Unreleased]\n\n### Added\n\n- feat: later\n\n## [1.2.5] - 2024-10-18\n\n### Fixed\n\n- fix: released\n"
+	if actual := releaseUnreleasedEntries(branch, rc, "1.2.5", date); actual != expected {
+		t.Errorf("Expected:\n%s\nbut got:\n%s", expected, actual)
+	}
+}
+
+func TestIsAutodeployerCommit(t *testing.T) {
+	for message, expected := range map[string]bool{
+		"chore(changelog): add changes of 1.2.4-rc1 using autodeployer":                   true,
+		"chore(changelog): release api/1.2.4 using autodeployer\n":                        true,
+		"chore(changelog): add changes of api/1.2.4-rc1,web/1.3.0-rc2 using autodeployer": true,
+		"chore(changelog): release 1.2.4 using autodeployer":                              true,
+		"docs: explain how to deploy using autodeployer":                                  false,
+		"chore(changelog): release 1.2.4 and 1.2.5 using autodeployer":                    false,
+		"chore(changelog): add changes of 1.2.4-rc1 using autodeployer\n\nand more":       false,
+	} {
+		if actual := isAutodeployerCommit(message); actual != expected {
+			t.Errorf("Expected %v for %q but got %v", expected, message, actual)
+		}
+	}
+}
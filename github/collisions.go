@@ -0,0 +1,130 @@
+package gh
+
+import (
+	"fmt"
+
+	"github.com/google/go-github/v39/github"
+)
+
+// TagCollisionPolicy decides what happens when the computed tag already exists
+type TagCollisionPolicy string
+
+const (
+	// advance past the highest rc of the version
+	RenumberOnCollision TagCollisionPolicy = "renumber"
+	// stop and explain who got there first
+	FailOnCollision TagCollisionPolicy = "fail"
+)
+
+// ParseTagCollisionPolicy validates a policy from the config, defaulting to renumbering
+func ParseTagCollisionPolicy(policy string) (TagCollisionPolicy, error) {
+	switch TagCollisionPolicy(policy) {
+	case "":
+		return RenumberOnCollision, nil
+	case RenumberOnCollision, FailOnCollision:
+		return TagCollisionPolicy(policy), nil
+	}
+	return "", fmt.Errorf("unknown tag collision policy %q (expected %q or %q)", policy, RenumberOnCollision, FailOnCollision)
+}
+
+// resolveTagCollision returns newTag when it isn't taken. Otherwise a taken rc is renumbered past
+// the highest rc of its version, so it stays the latest of the line, and the fail policy explains
+// who took the tag, as described by origin.
+func resolveTagCollision(newTag string, taken map[string]bool, format TagFormat, policy TagCollisionPolicy, origin func(string) string) (string, error) {
+	if !taken[newTag] {
+		return newTag, nil
+	}
+	if policy == FailOnCollision {
+		by := ""
+		if description := origin(newTag); description != "" {
+			by = " (" + description + ")"
+		}
+		return "", fmt.Errorf("tag %s already exists%s; someone else probably released from this line since the old tag was determined. Pass the old tag explicitly or set tag_collision_policy to %q", newTag, by, RenumberOnCollision)
+	}
+	version, err := format.Parse(newTag)
+	if err != nil {
+		return "", err
+	}
+	if !version.IsPrerelease() {
+		return "", fmt.Errorf("tag %s already exists and final releases can't be renumbered", newTag)
+	}
+	highest := version
+	for tag := range taken {
+		other, err := format.Parse(tag)
+		if err != nil || !other.IsPrerelease() || other.Core().Compare(version.Core()) != 0 {
+			continue
+		}
+		if other.Compare(highest) > 0 {
+			highest = other
+		}
+	}
+	next, err := highest.nextPrerelease()
+	if err != nil {
+		return "", err
+	}
+	return format.Render(next), nil
+}
+
+// describeTagOrigin tells who created an existing tag and when: the author of its release, the
+// tagger of an annotated tag or the author of its commit. It's empty when that can't be read.
+func describeTagOrigin(tag string) string {
+	release, _, err := Globals.Client.Repositories.GetReleaseByTag(Globals.Ctx, Globals.Owner, Globals.Repo, tag)
+	if err == nil && release.GetAuthor().GetLogin() != "" {
+		return fmt.Sprintf("released by @%s on %s", release.GetAuthor().GetLogin(), release.GetCreatedAt().Format("2006-01-02 15:04"))
+	}
+	ref, _, err := Globals.Client.Git.GetRef(Globals.Ctx, Globals.Owner, Globals.Repo, "refs/tags/"+tag)
+	if err != nil {
+		return ""
+	}
+	if ref.GetObject().GetType() == "tag" {
+		annotatedTag, _, err := Globals.Client.Git.GetTag(Globals.Ctx, Globals.Owner, Globals.Repo, ref.GetObject().GetSHA())
+		if err != nil {
+			return ""
+		}
+		return fmt.Sprintf("tagged by %s on %s", annotatedTag.GetTagger().GetName(), annotatedTag.GetTagger().GetDate().Format("2006-01-02 15:04"))
+	}
+	sha := ref.GetObject().GetSHA()
+	infos, err := resolveCommits(Globals.Ctx, getCommitCache(), []string{sha}, fetchCommitInfo)
+	if err != nil || infos[sha].Author == "" {
+		return ""
+	}
+	return fmt.Sprintf("on a commit by @%s from %s", infos[sha].Author, infos[sha].Date.Format("2006-01-02 15:04"))
+}
+
+// getTakenTags collects the names of all tags and release tags (including drafts) of the repo as
+// they are now, so that tags created since the start of the run count
+func getTakenTags() (map[string]bool, error) {
+	taken := map[string]bool{}
+	tags, err := listAllTagsREST(Globals.Repo)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching tags: %w", err)
+	}
+	for _, tag := range tags {
+		taken[tag.GetName()] = true
+	}
+	err = forEachReleaseREST(Globals.Repo, func(release *github.RepositoryRelease) bool {
+		taken[release.GetTagName()] = true
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error fetching releases: %w", err)
+	}
+	return taken, nil
+}
+
+// ensureTagIsFree checks the new tag against the existing tags and releases and applies
+// the collision policy when it's already taken
+func ensureTagIsFree(newTag string) (string, error) {
+	taken, err := getTakenTags()
+	if err != nil {
+		return "", err
+	}
+	freeTag, err := resolveTagCollision(newTag, taken, Globals.TagFormat, Globals.TagCollisionPolicy, describeTagOrigin)
+	if err != nil {
+		return "", err
+	}
+	if freeTag != newTag {
+		fmt.Printf("Tag %s already exists, using %s instead.\n", newTag, freeTag)
+	}
+	return freeTag, nil
+}
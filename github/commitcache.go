@@ -0,0 +1,194 @@
+package gh
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v39/github"
+)
+
+// the number of commits resolved in parallel
+const commitWorkers = 8
+
+// commitInfo is what the tag filters need to know about a tagged commit
+type commitInfo struct {
+	Author string    `json:"author"`
+	Date   time.Time `json:"date"`
+}
+
+// commitCache maps commit SHAs to their author and date. Commits are immutable, so entries
+// never expire and are persisted between runs when a cache directory is configured.
+type commitCache struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]commitInfo
+	dirty   bool
+}
+
+var commits *commitCache
+
+// the subdirectory of the cache directory holding the commit caches, out of the reach of the
+// eviction of the response cache since commits never change
+const commitCacheDir = "commits"
+
+func getCommitCache() *commitCache {
+	if commits == nil {
+		commits = loadCommitCache(Globals.CacheDir)
+	}
+	return commits
+}
+
+func loadCommitCache(dir string) *commitCache {
+	cache := &commitCache{entries: map[string]commitInfo{}}
+	if dir == "" {
+		return cache
+	}
+	cache.path = filepath.Join(dir, commitCacheDir, fmt.Sprintf("%s-%s.json", Globals.Owner, Globals.Repo))
+	data, err := os.ReadFile(cache.path)
+	if err != nil {
+		return cache
+	}
+	if err := json.Unmarshal(data, &cache.entries); err != nil {
+		fmt.Printf("Ignoring corrupt commit cache %s: %s\n", cache.path, err)
+		cache.entries = map[string]commitInfo{}
+	}
+	return cache
+}
+
+func (c *commitCache) get(sha string) (commitInfo, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	info, ok := c.entries[sha]
+	return info, ok
+}
+
+func (c *commitCache) set(sha string, info commitInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[sha] = info
+	c.dirty = true
+}
+
+func (c *commitCache) save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.path == "" || !c.dirty {
+		return nil
+	}
+	data, err := json.Marshal(c.entries)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o700); err != nil {
+		return err
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		return err
+	}
+	c.dirty = false
+	return nil
+}
+
+// resolveCommits looks up every SHA with a bounded pool of workers, skipping the ones already
+// in the cache. The first error (or a cancelled context) stops the remaining lookups.
+func resolveCommits(ctx context.Context, cache *commitCache, shas []string, fetch func(context.Context, string) (commitInfo, error)) (map[string]commitInfo, error) {
+	resolved := map[string]commitInfo{}
+	var missing []string
+	for _, sha := range shas {
+		if _, ok := resolved[sha]; ok {
+			continue
+		}
+		if info, ok := cache.get(sha); ok {
+			resolved[sha] = info
+			continue
+		}
+		resolved[sha] = commitInfo{}
+		missing = append(missing, sha)
+	}
+	if len(missing) == 0 {
+		return resolved, nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	jobs := make(chan string)
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	workers := commitWorkers
+	if len(missing) < workers {
+		workers = len(missing)
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for sha := range jobs {
+				info, err := fetch(ctx, sha)
+				mu.Lock()
+				if err != nil {
+					if firstErr == nil {
+						firstErr = fmt.Errorf("error fetching commit %s: %w", sha, err)
+						cancel()
+					}
+				} else {
+					resolved[sha] = info
+					cache.set(sha, info)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+feed:
+	for _, sha := range missing {
+		select {
+		case jobs <- sha:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := cache.save(); err != nil {
+		fmt.Printf("Failed to save the commit cache: %s\n", err)
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return resolved, nil
+}
+
+func fetchCommitInfo(ctx context.Context, sha string) (commitInfo, error) {
+	commit, _, err := Globals.Client.Repositories.GetCommit(ctx, Globals.Owner, Globals.Repo, sha, nil)
+	if err != nil {
+		return commitInfo{}, err
+	}
+	return commitInfo{
+		Author: commit.GetAuthor().GetLogin(),
+		Date:   commit.GetCommit().GetCommitter().GetDate(),
+	}, nil
+}
+
+// resolveTagCommits returns the author and date of the commit behind every tag
+func resolveTagCommits(tags []*github.RepositoryTag) (map[string]commitInfo, error) {
+	shas := make([]string, len(tags))
+	for i, tag := range tags {
+		shas[i] = tag.GetCommit().GetSHA()
+	}
+	return resolveCommits(Globals.Ctx, getCommitCache(), shas, fetchCommitInfo)
+}
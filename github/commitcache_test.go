@@ -0,0 +1,71 @@
+package gh
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestResolveCommits(t *testing.T) {
+	dir := t.TempDir()
+	date := time.Date(2024, time.October, 1, 0, 0, 0, 0, time.UTC)
+	var fetched int32
+	fetch := func(ctx context.Context, sha string) (commitInfo, error) {
+		atomic.AddInt32(&fetched, 1)
+		return commitInfo{Author: "author-" + sha, Date: date}, nil
+	}
+
+	shas := []string{"a", "b", "a", "c", "d", "e", "f", "g", "h", "i", "j", "b"}
+	resolved, err := resolveCommits(context.Background(), loadCommitCache(dir), shas, fetch)
+	if err != nil {
+		t.Fatalf("Error returned from resolveCommits: %v", err)
+	}
+	if fetched != 10 {
+		t.Errorf("Expected every distinct commit to be fetched once but got %d fetches", fetched)
+	}
+	for _, sha := range shas {
+		if resolved[sha].Author != "author-"+sha || !resolved[sha].Date.Equal(date) {
+			t.Errorf("Unexpected commit info for %s: %+v", sha, resolved[sha])
+		}
+	}
+
+	// a fresh cache loaded from the same directory shouldn't need any API calls
+	resolved, err = resolveCommits(context.Background(), loadCommitCache(dir), []string{"a", "j", "k"}, fetch)
+	if err != nil {
+		t.Fatalf("Error returned from resolveCommits: %v", err)
+	}
+	if fetched != 11 || resolved["j"].Author != "author-j" || resolved["k"].Author != "author-k" {
+		t.Errorf("Expected only the new commit to be fetched but got %d fetches and %+v", fetched, resolved)
+	}
+}
+
+func TestResolveCommitsStopsOnError(t *testing.T) {
+	var fetched int32
+	fetch := func(ctx context.Context, sha string) (commitInfo, error) {
+		atomic.AddInt32(&fetched, 1)
+		if sha == "bad" {
+			return commitInfo{}, errors.New("boom")
+		}
+		<-ctx.Done()
+		return commitInfo{}, ctx.Err()
+	}
+
+	shas := []string{"bad"}
+	for i := 0; i < 100; i++ {
+		shas = append(shas, string(rune('A'+i)))
+	}
+	if _, err := resolveCommits(context.Background(), loadCommitCache(""), shas, fetch); err == nil {
+		t.Fatal("Expected an error from resolveCommits")
+	}
+	if fetched > commitWorkers {
+		t.Errorf("Expected the remaining lookups to be cancelled but got %d fetches", fetched)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := resolveCommits(ctx, loadCommitCache(""), []string{"x", "y"}, fetch); err == nil {
+		t.Error("Expected an error from resolveCommits with a cancelled context")
+	}
+}
@@ -0,0 +1,109 @@
+package gh
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/go-github/v39/github"
+)
+
+// matches the header of a conventional commit, e.g. `feat(api)!: add endpoint`
+var conventionalCommitHeader = regexp.MustCompile(`^(\w+)(?:\([^)]*\))?(!)?:\s`)
+
+// matches a `BREAKING CHANGE:` (or `BREAKING-CHANGE:`) footer
+var breakingChangeFooter = regexp.MustCompile(`(?m)^BREAKING[ -]CHANGE:\s`)
+
+// parseConventionalCommit returns the lowercased type of a conventional commit message and
+// whether it declares a breaking change. The type is empty for non-conventional messages.
+func parseConventionalCommit(message string) (string, bool) {
+	header := strings.SplitN(strings.TrimSpace(message), "\n", 2)[0]
+	breaking := breakingChangeFooter.MatchString(message)
+	match := conventionalCommitHeader.FindStringSubmatch(header)
+	if match == nil {
+		return "", breaking
+	}
+	return strings.ToLower(match[1]), breaking || match[2] == "!"
+}
+
+// versionChangeTypeOf maps a single commit message to the change it requires.
+// It returns false for messages that don't follow the conventional commit format.
+func versionChangeTypeOf(message string) (VersionChangeType, bool) {
+	commitType, breaking := parseConventionalCommit(message)
+	switch {
+	case breaking:
+		return Breaking, true
+	case commitType == "feat":
+		return Major, true
+	case commitType == "":
+		return Minor, false
+	}
+	return Minor, true
+}
+
+var versionChangeRank = map[VersionChangeType]int{Minor: 0, Major: 1, Breaking: 2}
+
+// inferVersionChangeType picks the largest change required by the given commit messages
+// and returns the indexes of the messages that drove the decision
+func inferVersionChangeType(messages []string) (VersionChangeType, []int) {
+	versionChangeType := Minor
+	var drivers []int
+	for i, message := range messages {
+		changeType, ok := versionChangeTypeOf(message)
+		if !ok {
+			continue
+		}
+		if versionChangeRank[changeType] > versionChangeRank[versionChangeType] {
+			versionChangeType = changeType
+			drivers = nil
+		}
+		if changeType == versionChangeType {
+			drivers = append(drivers, i)
+		}
+	}
+	return versionChangeType, drivers
+}
+
+// getCommitsBetween lists the commits reachable from head but not from base
+func getCommitsBetween(base string, head string) ([]*github.RepositoryCommit, error) {
+	var commits []*github.RepositoryCommit
+	options := &github.ListOptions{PerPage: 100}
+	for {
+		comparison, resp, err := Globals.Client.Repositories.CompareCommits(Globals.Ctx, Globals.Owner, Globals.Repo, base, head, options)
+		if err != nil {
+			return nil, fmt.Errorf("error comparing %s...%s: %w", base, head, err)
+		}
+		commits = append(commits, comparison.Commits...)
+		if resp.NextPage == 0 {
+			break
+		}
+		options.Page = resp.NextPage
+	}
+	return commits, nil
+}
+
+// determineVersionChangeType reads the conventional commits between the old tag and the head
+// of the branch and decides how big the version bump should be
+func determineVersionChangeType(oldTag string) (VersionChangeType, error) {
+	commits, err := getCommitsBetween(oldTag, headRef())
+	if err != nil {
+		return "", err
+	}
+	messages := make([]string, len(commits))
+	for i, commit := range commits {
+		messages[i] = commit.GetCommit().GetMessage()
+	}
+	versionChangeType, drivers := inferVersionChangeType(messages)
+
+	fmt.Printf("Inferred a %s version change from %d commit(s) between %s and %s", versionChangeType, len(commits), oldTag, Globals.Branch)
+	if len(drivers) == 0 {
+		fmt.Println(" (no conventional commits found)")
+		return versionChangeType, nil
+	}
+	fmt.Println(", driven by:")
+	for _, i := range drivers {
+		header := strings.SplitN(strings.TrimSpace(messages[i]), "\n", 2)[0]
+		fmt.Printf("- %.7s %s\n", commits[i].GetSHA(), header)
+	}
+	return versionChangeType, nil
+}
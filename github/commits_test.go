@@ -0,0 +1,54 @@
+package gh
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseConventionalCommit(t *testing.T) {
+	testCases := []struct {
+		message          string
+		expectedType     string
+		expectedBreaking bool
+	}{
+		{"feat: add login page", "feat", false},
+		{"fix(api): handle nil pointer", "fix", false},
+		{"Feat: capitalised type", "feat", false},
+		{"feat!: drop v1 endpoints", "feat", true},
+		{"refactor(core)!: rename config keys", "refactor", true},
+		{"chore: bump deps\n\nBREAKING CHANGE: requires go 1.22", "chore", true},
+		{"fix: typo\n\nBREAKING-CHANGE: it was load bearing", "fix", true},
+		{"Merge pull request #12 from foo/bar", "", false},
+		{"feat:missing space", "", false},
+		{"update readme\n\nBREAKING CHANGE: not really", "", true},
+	}
+
+	for _, tc := range testCases {
+		commitType, breaking := parseConventionalCommit(tc.message)
+		if commitType != tc.expectedType || breaking != tc.expectedBreaking {
+			t.Errorf("parseConventionalCommit(%q): expected (%s, %t) but got (%s, %t)", tc.message, tc.expectedType, tc.expectedBreaking, commitType, breaking)
+		}
+	}
+}
+
+func TestInferVersionChangeType(t *testing.T) {
+	testCases := []struct {
+		messages        []string
+		expected        VersionChangeType
+		expectedDrivers []int
+	}{
+		{nil, Minor, nil},
+		{[]string{"wip", "more wip"}, Minor, nil},
+		{[]string{"fix: a", "docs: b", "wip"}, Minor, []int{0, 1}},
+		{[]string{"fix: a", "feat: b", "feat(ui): c"}, Major, []int{1, 2}},
+		{[]string{"feat: a", "fix!: b", "feat: c"}, Breaking, []int{1}},
+		{[]string{"chore: a\n\nBREAKING CHANGE: b", "feat: c"}, Breaking, []int{0}},
+	}
+
+	for _, tc := range testCases {
+		actual, drivers := inferVersionChangeType(tc.messages)
+		if actual != tc.expected || !reflect.DeepEqual(drivers, tc.expectedDrivers) {
+			t.Errorf("inferVersionChangeType(%q): expected (%s, %v) but got (%s, %v)", tc.messages, tc.expected, tc.expectedDrivers, actual, drivers)
+		}
+	}
+}
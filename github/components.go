@@ -0,0 +1,81 @@
+package gh
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v39/github"
+)
+
+// the most files the compare API lists, a comparison reaching it may be missing some
+const compareFilesLimit = 300
+
+// ComponentTagFormat prefixes the tag format of a monorepo component (e.g. `api/` for `api/1.2.3`)
+func ComponentTagFormat(prefix string, template string, prerelease string) (TagFormat, error) {
+	if prefix == "" {
+		return NewTagFormat(template, prerelease)
+	}
+	if template == "" {
+		template = defaultTagTemplate
+	}
+	return NewTagFormat(prefix+template, prerelease)
+}
+
+// imageTag is the image tag of a release tag. Image tags can't contain the `/` of component
+// prefixes, so the images of `api/1.2.3` are tagged `1.2.3`.
+func imageTag(tag string) string {
+	return strings.TrimPrefix(tag, Globals.TagPrefix)
+}
+
+// releaseTag is the release tag of an image tag
+func releaseTag(imageTag string) string {
+	return Globals.TagPrefix + imageTag
+}
+
+// changedUnder reports whether one of the files is inside the directory
+func changedUnder(files []string, dir string) bool {
+	dir = strings.Trim(dir, "/")
+	if dir == "" || dir == "." {
+		return len(files) > 0
+	}
+	for _, file := range files {
+		if file == dir || strings.HasPrefix(file, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// HasChangesSince reports whether the component path changed between the old tag and the
+// resolved head. Components without a path always have changes.
+func HasChangesSince(oldTag string) (bool, error) {
+	if Globals.ComponentPath == "" {
+		return true, nil
+	}
+	seen := map[string]bool{}
+	var files []string
+	options := &github.ListOptions{PerPage: 100}
+	for {
+		comparison, resp, err := Globals.Client.Repositories.CompareCommits(Globals.Ctx, Globals.Owner, Globals.Repo, oldTag, headRef(), options)
+		if err != nil {
+			return false, fmt.Errorf("error comparing %s...%s: %w", oldTag, headRef(), err)
+		}
+		if len(comparison.Files) >= compareFilesLimit {
+			// the change list is truncated, don't risk skipping the component
+			return true, nil
+		}
+		for _, file := range comparison.Files {
+			for _, name := range []string{file.GetFilename(), file.GetPreviousFilename()} {
+				if name != "" && !seen[name] {
+					seen[name] = true
+					files = append(files, name)
+				}
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		options.Page = resp.NextPage
+	}
+	return changedUnder(files, Globals.ComponentPath), nil
+}
@@ -0,0 +1,67 @@
+package gh
+
+import (
+	"testing"
+	"time"
+)
+
+func TestChangedUnder(t *testing.T) {
+	testCases := []struct {
+		files    []string
+		dir      string
+		expected bool
+	}{
+		{[]string{"services/api/main.go", "README.md"}, "services/api", true},
+		{[]string{"services/api/main.go"}, "services/api/", true},
+		{[]string{"services/api2/main.go", "services/apis.md"}, "services/api", false},
+		{[]string{"README.md"}, "", true},
+		{nil, "", false},
+		{nil, "services/api", false},
+	}
+
+	for _, tc := range testCases {
+		actual := changedUnder(tc.files, tc.dir)
+		if actual != tc.expected {
+			t.Errorf("changedUnder(%v, %q): expected %v but got %v", tc.files, tc.dir, tc.expected, actual)
+		}
+	}
+}
+
+func TestComponentTagFormat(t *testing.T) {
+	testCases := []struct {
+		prefix   string
+		template string
+		tag      string
+		matches  bool
+	}{
+		{"api/", "", "api/1.2.3-rc1", true},
+		{"api/", "", "1.2.3", false},
+		{"api/", "", "web/1.2.3", false},
+		{"web/", "v{major}.{minor}.{patch}", "web/v1.2.3", true},
+		{"", "", "1.2.3", true},
+	}
+
+	for _, tc := range testCases {
+		format, err := ComponentTagFormat(tc.prefix, tc.template, "")
+		if err != nil {
+			t.Errorf("ComponentTagFormat(%q, %q): unexpected error %v", tc.prefix, tc.template, err)
+			continue
+		}
+		if actual := format.Matches(tc.tag); actual != tc.matches {
+			t.Errorf("ComponentTagFormat(%q, %q).Matches(%s): expected %v but got %v", tc.prefix, tc.template, tc.tag, tc.matches, actual)
+		}
+	}
+
+	format, err := ComponentTagFormat("api/", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	version, err := format.Parse("api/1.2.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	next, err := format.next(version, Minor, time.Now())
+	if err != nil || format.Render(next) != "api/1.2.4-rc1" {
+		t.Errorf("next of api/1.2.3: expected api/1.2.4-rc1 but got %s (%v)", format.Render(next), err)
+	}
+}
@@ -0,0 +1,109 @@
+package gh
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/google/go-github/v39/github"
+)
+
+// OldTagSource decides where the old tag comes from
+type OldTagSource string
+
+const (
+	// guess the old tag from the tags and releases of the source repo
+	TagsOldTagSource OldTagSource = "tags"
+	// read the tag of the image currently referenced in the staging manifest of the deployments repo
+	DeployedOldTagSource OldTagSource = "deployed"
+)
+
+// ParseOldTagSource validates an old tag source from the config or the CLI, defaulting to tags
+func ParseOldTagSource(source string) (OldTagSource, error) {
+	switch OldTagSource(source) {
+	case "":
+		return TagsOldTagSource, nil
+	case TagsOldTagSource, DeployedOldTagSource:
+		return OldTagSource(source), nil
+	}
+	return "", fmt.Errorf("unknown old tag source %q (expected %q or %q)", source, TagsOldTagSource, DeployedOldTagSource)
+}
+
+// extractImageTag finds the tag of every `<imageURL>:<tag>` reference in the content and
+// fails unless they all agree
+func extractImageTag(content string, imageURL string) (string, error) {
+	// the reference starts the line or follows a separator, so that another image whose name ends
+	// with imageURL (e.g. `mirror.io/<imageURL>`) doesn't match. A docker tag is up to 128 word
+	// characters, dots and dashes, not starting with either.
+	pattern := regexp.MustCompile(`(?m)(?:^|[^\w./-])` + regexp.QuoteMeta(imageURL) + `:([\w][\w.-]{0,127})`)
+	tag := ""
+	for _, match := range pattern.FindAllStringSubmatch(content, -1) {
+		if tag != "" && tag != match[1] {
+			return "", fmt.Errorf("found conflicting tags %s and %s for %s", tag, match[1], imageURL)
+		}
+		tag = match[1]
+	}
+	if tag == "" {
+		return "", fmt.Errorf("no reference to %s found", imageURL)
+	}
+	return tag, nil
+}
+
+// readManifestTag reads the tag of the image of a manifest, failing unless its references agree
+func readManifestTag(manifest ManifestFile, content string) (string, error) {
+	_, tags, err := manifest.bump(content, "")
+	if err != nil {
+		return "", err
+	}
+	for _, tag := range tags {
+		if tag != tags[0] {
+			return "", fmt.Errorf("found conflicting tags %s and %s for %s", tags[0], tag, manifest.ImageURL)
+		}
+	}
+	if tags[0] == "" {
+		return "", fmt.Errorf("%s has no tag", manifest.ImageURL)
+	}
+	return tags[0], nil
+}
+
+// getFileContent reads a file from a repo of the owner at the given ref (the default branch when empty)
+func getFileContent(repo string, path string, ref string) (*github.RepositoryContent, string, error) {
+	var options *github.RepositoryContentGetOptions
+	if ref != "" {
+		options = &github.RepositoryContentGetOptions{Ref: ref}
+	}
+	fileContent, _, _, err := Globals.Client.Repositories.GetContents(Globals.Ctx, Globals.Owner, repo, path, options)
+	if err != nil {
+		return nil, "", err
+	}
+	if fileContent == nil {
+		return nil, "", fmt.Errorf("%s is a directory", path)
+	}
+	content, err := fileContent.GetContent()
+	if err != nil {
+		return nil, "", err
+	}
+	return fileContent, content, nil
+}
+
+// getDeployedTag reads the tag currently deployed through a manifest of the deployments repo
+func getDeployedTag(manifest ManifestFile) (string, error) {
+	path := manifest.Path
+	_, content, err := getFileContent(Globals.DeploymentsRepo, path, "")
+	if err != nil {
+		return "", fmt.Errorf("error reading %s from %s: %w", path, Globals.DeploymentsRepo, err)
+	}
+	var tag string
+	if manifest.Type == ImageManifest || manifest.Type == "" {
+		tag, err = extractImageTag(content, manifest.ImageURL)
+	} else {
+		tag, err = readManifestTag(manifest, content)
+	}
+	if err != nil {
+		return "", fmt.Errorf("error reading the deployed tag from %s: %w", path, err)
+	}
+	tag = releaseTag(tag)
+	if !Globals.TagFormat.Matches(tag) {
+		return "", fmt.Errorf("deployed tag %s does not follow the tag format of %s", tag, Globals.Repo)
+	}
+	return tag, nil
+}
@@ -0,0 +1,32 @@
+package gh
+
+import "testing"
+
+func TestExtractImageTag(t *testing.T) {
+	testCases := []struct {
+		content  string
+		expected string
+		wantErr  bool
+	}{
+		{"image: ghcr.io/org/app:1.2.3-rc2\n", "1.2.3-rc2", false},
+		{"containers:\n  - image: ghcr.io/org/app:v1.2.3\n    name: app\n  - image: ghcr.io/org/app-worker:9.9.9\n", "v1.2.3", false},
+		{"a: ghcr.io/org/app:1.2.3\nb: \"ghcr.io/org/app:1.2.3\"\n", "1.2.3", false},
+		{"a: ghcr.io/org/app:1.2.3\nb: ghcr.io/org/app:1.2.4\n", "", true},
+		{"image: ghcr.io/org/other:1.2.3\n", "", true},
+		// images whose name ends or starts with the configured one
+		{"- image: mirror.io/ghcr.io/org/app:9.9.9\n- image: ghcr.io/org/app:1.2.3\n", "1.2.3", false},
+		{"image: xghcr.io/org/app:9.9.9\n", "", true},
+		{"image: ghcr.io/org/app-worker:9.9.9\n", "", true},
+		{"ghcr.io/org/app:1.2.3\nargs: [\"--image=ghcr.io/org/app:1.2.3\"]\n", "1.2.3", false},
+	}
+
+	for _, tc := range testCases {
+		actual, err := extractImageTag(tc.content, "ghcr.io/org/app")
+		if (err != nil) != tc.wantErr {
+			t.Errorf("extractImageTag(%q): unexpected error %v", tc.content, err)
+		}
+		if actual != tc.expected {
+			t.Errorf("extractImageTag(%q): expected %s but got %s", tc.content, tc.expected, actual)
+		}
+	}
+}
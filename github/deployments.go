@@ -1,10 +1,8 @@
 package gh
 
 import (
-	"encoding/base64"
 	"fmt"
 	"os"
-	"strings"
 	"time"
 
 	"github.com/google/go-github/v39/github"
@@ -19,8 +17,25 @@ func filterWorkflowRunsByName(workflows []*github.WorkflowRun, name string) []*g
 	}
 	return filteredWorkflows
 }
-// waits for the image build workflow to complete
-func WaitForWorkflow(repo string, branch string) {
+
+// keeps the runs of a given commit, or every run when no commit is given
+func filterWorkflowRunsByHeadSHA(workflows []*github.WorkflowRun, headSHA string) []*github.WorkflowRun {
+	if headSHA == "" {
+		return workflows
+	}
+	var filteredWorkflows []*github.WorkflowRun
+	for _, workflow := range workflows {
+		if workflow.GetHeadSHA() == headSHA {
+			filteredWorkflows = append(filteredWorkflows, workflow)
+		}
+	}
+	return filteredWorkflows
+}
+// waits for the image build workflow of a branch or tag to complete and returns its conclusion
+// (`success`, `failure`, `cancelled`, ... or `timed_out` when it didn't complete within the retry
+// limit). When a head SHA is given, runs of other commits (e.g. pushed after the run started) are
+// ignored.
+func WaitForWorkflow(repo string, branch string, headSHA string) string {
 	// default values for parameters
 	if repo == "" {
 		repo = Globals.Repo
@@ -29,7 +44,6 @@ func WaitForWorkflow(repo string, branch string) {
 		branch = Globals.Branch
 	}
 	fmt.Println("Waiting for workflow completion...")
-	workflowComplete := false
 	options := &github.ListWorkflowRunsOptions{Branch: branch}
 	for i := 0; i < Globals.WorkflowRetryLimit; i++ {
 		workflows, _, err := Globals.Client.Actions.ListRepositoryWorkflowRuns(Globals.Ctx, Globals.Owner, repo, options)
@@ -37,33 +51,81 @@ func WaitForWorkflow(repo string, branch string) {
 			fmt.Printf("Error when fetching workflows: %s\n", err)
 			os.Exit(1)
 		}
-		if len(workflows.WorkflowRuns) == 0 {
+		workflowRuns := filterWorkflowRunsByHeadSHA(filterWorkflowRunsByName(workflows.WorkflowRuns, "Deploy"), headSHA)
+		if len(workflowRuns) == 0 {
 			fmt.Printf("\rNo workflows found. Time elapsed: %ds", (i+1)*Globals.WorkflowRetryWaitSeconds)
 			time.Sleep(time.Duration(Globals.WorkflowRetryWaitSeconds) * time.Second)
 			continue
 		}
-		workflowRuns := filterWorkflowRunsByName(workflows.WorkflowRuns, "Deploy")
 
 		workflowStatus := workflowRuns[0].GetStatus()
 		if workflowStatus == "completed" {
-			fmt.Println("\nWorkflow has successfully completed!")
-			workflowComplete = true
-			break
+			conclusion := workflowRuns[0].GetConclusion()
+			if conclusion == "success" {
+				fmt.Println("\nWorkflow has successfully completed!")
+			} else {
+				fmt.Printf("\nWorkflow has completed with conclusion: %s\n", conclusion)
+			}
+			return conclusion
 		} else {
 			fmt.Printf("\rWorkflow status: %s. Time elapsed: %ds", workflowStatus, (i+1)*Globals.WorkflowRetryWaitSeconds)
 			time.Sleep(time.Duration(Globals.WorkflowRetryWaitSeconds) * time.Second)
 		}
 	}
-	if !workflowComplete {
-		fmt.Println("Error: Workflow failed to complete within time limit.")
+	fmt.Println("Error: Workflow failed to complete within time limit.")
+	return "timed_out"
+}
+
+// bumps the image version in the deployment repository and returns the deployment branch and
+// its head, the commit the deployment workflow runs on
+func BumpDeployment(oldTag string, newTag string) (string, string) {
+	fmt.Printf("[3/5] Bumping image version in %s...\n", Globals.DeploymentsRepo)
+
+	futureTag, err := Globals.TagFormat.FinalTag(newTag)
+	if err != nil {
+		fmt.Printf("Failed to parse new tag %s: %s\n", newTag, err)
 		os.Exit(1)
 	}
+	newBranchNameRef := fmt.Sprintf("refs/heads/%s-%s-%s-bump-%s", getBranchUsername(), Globals.Repo, Globals.Branch, futureTag)
+	createDeploymentBranch(newBranchNameRef)
+	headSHA, err := bumpManifests(Globals.StagingManifests, oldTag, newTag, newBranchNameRef)
+	if err != nil {
+		fmt.Printf("Failed to bump the staging manifests: %s\n", err)
+		os.Exit(1)
+	}
+
+	return newBranchNameRef, headSHA
 }
 
-// bumps the image version in the deployment repository
-func BumpDeployment(oldTag string, newTag string) string {
-	fmt.Printf("[3/5] Bumping image version in %s...\n", Globals.DeploymentsRepo)
+// bumps the image version in the production manifest of the deployment repository and returns
+// the deployment branch and its head, the commit the deployment workflow runs on
+func BumpProductionDeployment(oldTag string, newTag string) (string, string) {
+	fmt.Printf("[3/5] Bumping production image version in %s...\n", Globals.DeploymentsRepo)
 
+	newBranchNameRef := fmt.Sprintf("refs/heads/%s-%s-promote-%s", getBranchUsername(), Globals.Repo, newTag)
+	createDeploymentBranch(newBranchNameRef)
+	headSHA, err := bumpManifests(Globals.ProductionManifests, oldTag, newTag, newBranchNameRef)
+	if err != nil {
+		fmt.Printf("Failed to bump the production manifests: %s\n", err)
+		os.Exit(1)
+	}
+
+	return newBranchNameRef, headSHA
+}
+
+// the username prefixing the deployment branches
+func getBranchUsername() string {
+	// TODO: add an option to enable/disable username in the branch name
+	username, err := getUsername()
+	if err != nil {
+		fmt.Println("Failed to get username, will use '' as the username for the new deployment branch")
+		username = ""
+	}
+	return username
+}
+
+// creates the branch off the default branch of the deployment repository, unless it already exists
+func createDeploymentBranch(newBranchNameRef string) {
 	// 0. Check if the deployment repo exists and get the default branch
 	deploymentsRepoGithub, _, err := Globals.Client.Repositories.Get(Globals.Ctx, Globals.Owner, Globals.DeploymentsRepo)
 	if err != nil {
@@ -82,14 +144,6 @@ func BumpDeployment(oldTag string, newTag string) string {
 	defaultBranchSHA := ref.Object.GetSHA()
 
 	// 2. Create new branch in deployment repo
-	futureTag := strings.Split(newTag, "-rc")[0]
-	// TODO: add an option to enable/disable username in the branch name
-	username, err := getUsername()
-	if err != nil {
-		fmt.Println("Failed to get username, will use '' as the username for the new deployment branch")
-		username = ""
-	}
-	newBranchNameRef := fmt.Sprintf("refs/heads/%s-%s-%s-bump-%s", username, Globals.Repo, Globals.Branch, futureTag)
 	newBranch := &github.Reference{
 		Ref:    &newBranchNameRef,
 		Object: &github.GitObject{SHA: &defaultBranchSHA},
@@ -105,39 +159,6 @@ func BumpDeployment(oldTag string, newTag string) string {
 	} else {
     fmt.Printf("Branch %s already exists\n", newBranchNameRef)
 	}
-
-	// 3. Get deployment YAML file from the repository
-	options := &github.RepositoryContentGetOptions{Ref: newBranchNameRef}
-	fileContent, _, _, err := Globals.Client.Repositories.GetContents(Globals.Ctx, Globals.Owner, Globals.DeploymentsRepo, Globals.DeploymentYAMLPath, options)
-	if err != nil {
-		fmt.Printf("Failed to get file contents: %s\n", err)
-		os.Exit(1)
-	}
-	decodedContent, err := base64.StdEncoding.DecodeString(*fileContent.Content)
-	if err != nil {
-		fmt.Println("Failed to decode file content:", err)
-		os.Exit(1)
-	}
-
-	// 4. Replace old tag with new tag in the content
-	contentStr := string(decodedContent)
-	newContentStr := strings.Replace(contentStr, oldTag, newTag, -1)
-
-	// 5. Push the updated content to the new branch
-	data := &github.RepositoryContentFileOptions{
-		Message: github.String(fmt.Sprintf("Image tag bumped to %s using autodeployer", newTag)),
-		Content: []byte(newContentStr),
-		SHA:     fileContent.SHA,
-		Branch:  &newBranchNameRef,
-	}
-	_, _, err = Globals.Client.Repositories.UpdateFile(Globals.Ctx, Globals.Owner, Globals.DeploymentsRepo, Globals.DeploymentYAMLPath, data)
-	if err != nil {
-		fmt.Printf("Failed to update the file %s: %s\n", Globals.DeploymentYAMLPath, err)
-		os.Exit(1)
-	}
-	fmt.Printf("Successfully bumped image version in %s!\n", Globals.DeploymentYAMLPath)
-
-	return newBranchNameRef
 }
 
 // triggers a workflow on the specified branch in the repository
@@ -0,0 +1,40 @@
+package gh
+
+import (
+	"testing"
+
+	"github.com/google/go-github/v39/github"
+)
+
+func TestFilterWorkflowRunsByHeadSHA(t *testing.T) {
+	runs := []*github.WorkflowRun{
+		{ID: github.Int64(3), HeadSHA: github.String("newer")},
+		{ID: github.Int64(2), HeadSHA: github.String("pinned")},
+		{ID: github.Int64(1), HeadSHA: github.String("older")},
+	}
+	testCases := []struct {
+		headSHA  string
+		expected []int64
+	}{
+		{"pinned", []int64{2}},
+		{"", []int64{3, 2, 1}},
+		{"unknown", nil},
+	}
+
+	for _, tc := range testCases {
+		var actual []int64
+		for _, run := range filterWorkflowRunsByHeadSHA(runs, tc.headSHA) {
+			actual = append(actual, run.GetID())
+		}
+		if len(actual) != len(tc.expected) {
+			t.Errorf("filterWorkflowRunsByHeadSHA(%q): expected %v but got %v", tc.headSHA, tc.expected, actual)
+			continue
+		}
+		for i := range actual {
+			if actual[i] != tc.expected[i] {
+				t.Errorf("filterWorkflowRunsByHeadSHA(%q): expected %v but got %v", tc.headSHA, tc.expected, actual)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package gh
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// dotenvVariable bumps a variable of a .env file holding the image or its tag
+type dotenvVariable struct {
+	ImageURL string
+	Name     string
+}
+
+var (
+	dotenvLine    = regexp.MustCompile(`^(\s*(?:export\s+)?)([A-Za-z_][A-Za-z0-9_.-]*)(\s*=\s*)(.*)$`)
+	dotenvComment = regexp.MustCompile(`\s#`)
+)
+
+// dotenvValue locates the value in what follows the `=` of a line, inside its quotes if any and
+// before a trailing comment
+func dotenvValue(rest string) (int, int, error) {
+	if rest != "" && (rest[0] == '"' || rest[0] == '\'') {
+		for i := 1; i < len(rest); i++ {
+			if rest[0] == '"' && rest[i] == '\\' {
+				i++
+				continue
+			}
+			if rest[i] == rest[0] {
+				return 1, i, nil
+			}
+		}
+		return 0, 0, fmt.Errorf("unterminated or multi-line value")
+	}
+	end := len(rest)
+	if comment := dotenvComment.FindStringIndex(rest); comment != nil {
+		end = comment[0]
+	}
+	return 0, len(strings.TrimRight(rest[:end], " \t\r")), nil
+}
+
+func (d dotenvVariable) Bump(content string, newTag string) (string, []string, error) {
+	lines := strings.Split(content, "\n")
+	var previousTags []string
+	for i, line := range lines {
+		match := dotenvLine.FindStringSubmatchIndex(line)
+		if match == nil || line[match[4]:match[5]] != d.Name {
+			continue
+		}
+		rest := line[match[8]:]
+		start, end, err := dotenvValue(rest)
+		if err != nil {
+			return "", nil, fmt.Errorf("can't rewrite %s at line %d: %w", d.Name, i+1, err)
+		}
+		bumped, previousTag, err := bumpTagValue(rest[start:end], d.ImageURL, newTag)
+		if err != nil {
+			return "", nil, fmt.Errorf("%s at line %d: %w", d.Name, i+1, err)
+		}
+		lines[i] = line[:match[8]] + rest[:start] + bumped + rest[end:]
+		previousTags = append(previousTags, previousTag)
+	}
+	if len(previousTags) == 0 {
+		return "", nil, fmt.Errorf("no variable %s found", d.Name)
+	}
+	return strings.Join(lines, "\n"), previousTags, nil
+}
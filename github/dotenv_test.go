@@ -0,0 +1,38 @@
+package gh
+
+import "testing"
+
+func TestDotenvVariableGolden(t *testing.T) {
+	testCases := []struct {
+		input        string
+		golden       string
+		manifest     ManifestFile
+		previousTags []string
+	}{
+		{"staging.env", "staging-image.env.golden", ManifestFile{Type: DotenvManifest, ImageURL: "ghcr.io/org/app", KeyPath: "APP_IMAGE"}, []string{"1.2.3"}},
+		{"staging.env", "staging-tag.env.golden", ManifestFile{Type: DotenvManifest, KeyPath: "APP_TAG"}, []string{"1.2.3"}},
+	}
+
+	for _, tc := range testCases {
+		checkGolden(t, tc.input, tc.golden, tc.manifest, tc.previousTags)
+	}
+}
+
+func TestDotenvVariable(t *testing.T) {
+	testCases := []struct {
+		name    string
+		content string
+	}{
+		{"another image", "APP_IMAGE=ghcr.io/org/worker:1.2.3\n"},
+		{"not a tag", "APP_IMAGE=\"ghcr.io/org/app 1.2.3\"\n"},
+		{"multi-line", "APP_IMAGE=\"ghcr.io/org/app:1.2.3\n\"\n"},
+		{"missing", "APP_IMAGE_TAG=1.2.3\n"},
+	}
+
+	for _, tc := range testCases {
+		_, _, err := dotenvVariable{ImageURL: "ghcr.io/org/app", Name: "APP_IMAGE"}.Bump(tc.content, "1.2.4-rc1")
+		if err == nil {
+			t.Errorf("%s: expected an error", tc.name)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package gh
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v39/github"
+)
+
+// createCommit commits the given files (path -> content) on top of the parent commit through
+// the git data API (blobs -> tree -> commit) and returns the SHA of the new commit.
+// No ref is moved; that's up to the caller.
+func createCommit(repo string, parentSHA string, files map[string]string, message string) (string, error) {
+	parent, _, err := Globals.Client.Git.GetCommit(Globals.Ctx, Globals.Owner, repo, parentSHA)
+	if err != nil {
+		return "", err
+	}
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	modes, err := fileModes(repo, parent.GetTree().GetSHA(), paths)
+	if err != nil {
+		return "", err
+	}
+	var entries []*github.TreeEntry
+	for _, path := range paths {
+		blob, _, err := Globals.Client.Git.CreateBlob(Globals.Ctx, Globals.Owner, repo, &github.Blob{
+			Content:  github.String(files[path]),
+			Encoding: github.String("utf-8"),
+		})
+		if err != nil {
+			return "", err
+		}
+		// existing files keep their mode (e.g. executable scripts)
+		mode := modes[path]
+		if mode == "" {
+			mode = "100644"
+		}
+		entries = append(entries, &github.TreeEntry{
+			Path: github.String(path),
+			Mode: github.String(mode),
+			Type: github.String("blob"),
+			SHA:  blob.SHA,
+		})
+	}
+	tree, _, err := Globals.Client.Git.CreateTree(Globals.Ctx, Globals.Owner, repo, parent.GetTree().GetSHA(), entries)
+	if err != nil {
+		return "", err
+	}
+	commit, _, err := Globals.Client.Git.CreateCommit(Globals.Ctx, Globals.Owner, repo, &github.Commit{
+		Message: github.String(message),
+		Tree:    tree,
+		Parents: []*github.Commit{{SHA: github.String(parentSHA)}},
+	})
+	if err != nil {
+		return "", err
+	}
+	return commit.GetSHA(), nil
+}
+
+// fileModes reads the modes of the given files in a tree. Files that don't exist yet are left out.
+func fileModes(repo string, treeSHA string, paths []string) (map[string]string, error) {
+	tree, _, err := Globals.Client.Git.GetTree(Globals.Ctx, Globals.Owner, repo, treeSHA, true)
+	if err != nil {
+		return nil, err
+	}
+	wanted := map[string]bool{}
+	for _, path := range paths {
+		wanted[path] = true
+	}
+	modes := map[string]string{}
+	for _, entry := range tree.Entries {
+		if wanted[entry.GetPath()] && entry.GetType() == "blob" {
+			modes[entry.GetPath()] = entry.GetMode()
+		}
+	}
+	if !tree.GetTruncated() {
+		return modes, nil
+	}
+	// the tree is too large to be listed at once, walk down to the missing files instead
+	for _, path := range paths {
+		if _, ok := modes[path]; ok {
+			continue
+		}
+		mode, err := walkFileMode(repo, treeSHA, path)
+		if err != nil {
+			return nil, err
+		}
+		if mode != "" {
+			modes[path] = mode
+		}
+	}
+	return modes, nil
+}
+
+// walkFileMode reads the mode of a file one directory at a time, empty when it doesn't exist
+func walkFileMode(repo string, treeSHA string, path string) (string, error) {
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		tree, _, err := Globals.Client.Git.GetTree(Globals.Ctx, Globals.Owner, repo, treeSHA, false)
+		if err != nil {
+			return "", err
+		}
+		found := false
+		for _, entry := range tree.Entries {
+			if entry.GetPath() != part {
+				continue
+			}
+			if i == len(parts)-1 {
+				if entry.GetType() != "blob" {
+					return "", nil
+				}
+				return entry.GetMode(), nil
+			}
+			treeSHA, found = entry.GetSHA(), true
+			break
+		}
+		if !found {
+			return "", nil
+		}
+	}
+	return "", nil
+}
+
+// isNotFound reports whether an API error is a 404
+func isNotFound(err error) bool {
+	var errorResponse *github.ErrorResponse
+	return errors.As(err, &errorResponse) && errorResponse.Response != nil && errorResponse.Response.StatusCode == http.StatusNotFound
+}
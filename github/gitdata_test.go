@@ -0,0 +1,80 @@
+package gh
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v39/github"
+)
+
+func TestCreateCommitKeepsFileModes(t *testing.T) {
+	for _, truncated := range []bool{false, true} {
+		var modes map[string]string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := r.Method + " " + strings.TrimPrefix(r.URL.Path, "/api/v3/repos/o/deploy/")
+			switch {
+			case route == "GET git/commits/parent":
+				io.WriteString(w, `{"sha":"parent","tree":{"sha":"root"}}`)
+			case route == "GET git/trees/root" && r.URL.Query().Get("recursive") != "":
+				if truncated {
+					io.WriteString(w, `{"sha":"root","truncated":true,"tree":[]}`)
+					return
+				}
+				io.WriteString(w, `{"sha":"root","tree":[
+					{"path":"scripts","mode":"040000","type":"tree","sha":"scripts"},
+					{"path":"scripts/deploy.sh","mode":"100755","type":"blob","sha":"a"},
+					{"path":"staging.yaml","mode":"100644","type":"blob","sha":"b"}
+				]}`)
+			case route == "GET git/trees/root":
+				io.WriteString(w, `{"sha":"root","tree":[
+					{"path":"scripts","mode":"040000","type":"tree","sha":"scripts"},
+					{"path":"staging.yaml","mode":"100644","type":"blob","sha":"b"}
+				]}`)
+			case route == "GET git/trees/scripts":
+				io.WriteString(w, `{"sha":"scripts","tree":[{"path":"deploy.sh","mode":"100755","type":"blob","sha":"a"}]}`)
+			case route == "POST git/blobs":
+				io.WriteString(w, `{"sha":"blob"}`)
+			case route == "POST git/trees":
+				var body struct {
+					Tree []*github.TreeEntry `json:"tree"`
+				}
+				json.NewDecoder(r.Body).Decode(&body)
+				modes = map[string]string{}
+				for _, entry := range body.Tree {
+					modes[entry.GetPath()] = entry.GetMode()
+				}
+				io.WriteString(w, `{"sha":"newtree"}`)
+			case route == "POST git/commits":
+				io.WriteString(w, `{"sha":"commit"}`)
+			default:
+				t.Errorf("Unexpected request %s", route)
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+
+		previous := Globals
+		client := github.NewClient(nil)
+		client.BaseURL, _ = url.Parse(server.URL + "/api/v3/")
+		Globals = AppContext{Owner: "o", Ctx: context.Background(), Client: client}
+		files := map[string]string{"scripts/deploy.sh": "#!/bin/sh\n", "staging.yaml": "image: app:1.2.4\n", "new.yaml": "{}\n"}
+		_, err := createCommit("deploy", "parent", files, "bump")
+		Globals = previous
+		server.Close()
+
+		if err != nil {
+			t.Fatalf("Error returned from createCommit (truncated: %v): %v", truncated, err)
+		}
+		expected := map[string]string{"scripts/deploy.sh": "100755", "staging.yaml": "100644", "new.yaml": "100644"}
+		for path, mode := range expected {
+			if modes[path] != mode {
+				t.Errorf("Expected %s to be committed with mode %s (truncated: %v) but got %s", path, mode, truncated, modes[path])
+			}
+		}
+	}
+}
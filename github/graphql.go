@@ -0,0 +1,243 @@
+package gh
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/go-github/v39/github"
+)
+
+// TagSource decides which API the tags and releases of the source repo are read from
+type TagSource string
+
+const (
+	// one REST call per page of tags plus one per tagged commit
+	RESTTagSource TagSource = "rest"
+	// tags, their commits, authors, dates and releases in a few batched GraphQL queries
+	GraphQLTagSource TagSource = "graphql"
+)
+
+// ParseTagSource validates a tag source from the config, defaulting to REST
+func ParseTagSource(source string) (TagSource, error) {
+	switch TagSource(source) {
+	case "":
+		return RESTTagSource, nil
+	case RESTTagSource, GraphQLTagSource:
+		return TagSource(source), nil
+	}
+	return "", fmt.Errorf("unknown tag source %q (expected %q or %q)", source, RESTTagSource, GraphQLTagSource)
+}
+
+const tagsQuery = `query($owner: String!, $repo: String!, $cursor: String) {
+  repository(owner: $owner, name: $repo) {
+    refs(refPrefix: "refs/tags/", first: 100, after: $cursor, orderBy: {field: TAG_COMMIT_DATE, direction: DESC}) {
+      pageInfo { hasNextPage endCursor }
+      nodes {
+        name
+        target {
+          ...commitFields
+          ... on Tag { target { ...commitFields } }
+        }
+      }
+    }
+  }
+}
+
+fragment commitFields on Commit {
+  oid
+  committedDate
+  author { user { login } }
+}`
+
+const releasesQuery = `query($owner: String!, $repo: String!, $cursor: String) {
+  repository(owner: $owner, name: $repo) {
+    releases(first: 100, after: $cursor, orderBy: {field: CREATED_AT, direction: DESC}) {
+      pageInfo { hasNextPage endCursor }
+      nodes {
+        databaseId
+        tagName
+        name
+        description
+        isPrerelease
+        isDraft
+        tagCommit { oid }
+      }
+    }
+  }
+}`
+
+type graphQLPageInfo struct {
+	HasNextPage bool   `json:"hasNextPage"`
+	EndCursor   string `json:"endCursor"`
+}
+
+type graphQLCommit struct {
+	Oid           string    `json:"oid"`
+	CommittedDate time.Time `json:"committedDate"`
+	Author        struct {
+		User *struct {
+			Login string `json:"login"`
+		} `json:"user"`
+	} `json:"author"`
+}
+
+type graphQLTagsResponse struct {
+	Repository struct {
+		Refs struct {
+			PageInfo graphQLPageInfo `json:"pageInfo"`
+			Nodes    []struct {
+				Name   string `json:"name"`
+				Target struct {
+					graphQLCommit
+					// set when the ref points at an annotated tag
+					Target *graphQLCommit `json:"target"`
+				} `json:"target"`
+			} `json:"nodes"`
+		} `json:"refs"`
+	} `json:"repository"`
+}
+
+type graphQLReleasesResponse struct {
+	Repository struct {
+		Releases struct {
+			PageInfo graphQLPageInfo `json:"pageInfo"`
+			Nodes    []struct {
+				DatabaseID   int64  `json:"databaseId"`
+				TagName      string `json:"tagName"`
+				Name         string `json:"name"`
+				Description  string `json:"description"`
+				IsPrerelease bool   `json:"isPrerelease"`
+				IsDraft      bool   `json:"isDraft"`
+				TagCommit    *struct {
+					Oid string `json:"oid"`
+				} `json:"tagCommit"`
+			} `json:"nodes"`
+		} `json:"releases"`
+	} `json:"repository"`
+}
+
+type graphQLError struct {
+	Message string `json:"message"`
+}
+
+// the tags and releases discovered through GraphQL, reused for the rest of the run. Checks that
+// need the current state (e.g. tag collisions) read them through REST instead.
+var (
+	tagsDiscovered     bool
+	discoveredTags     []*github.RepositoryTag
+	discoveredReleases []*github.RepositoryRelease
+)
+
+// graphQL runs a query against the GraphQL endpoint next to the REST API of the client
+func graphQL(query string, variables map[string]interface{}, data interface{}) error {
+	// `../graphql` resolves to /graphql on github.com and /api/graphql on GitHub Enterprise
+	req, err := Globals.Client.NewRequest("POST", "../graphql", map[string]interface{}{
+		"query":     query,
+		"variables": variables,
+	})
+	if err != nil {
+		return err
+	}
+	var resp struct {
+		Data   interface{}    `json:"data"`
+		Errors []graphQLError `json:"errors"`
+	}
+	resp.Data = data
+	if _, err := Globals.Client.Do(Globals.Ctx, req, &resp); err != nil {
+		return err
+	}
+	if len(resp.Errors) > 0 {
+		return fmt.Errorf("graphql: %s", resp.Errors[0].Message)
+	}
+	return nil
+}
+
+// discoverTagsGraphQL pulls every tag with its commit author and date, and every release,
+// in pages of 100. The commit details are fed into the commit cache so that the tag filters
+// don't need any REST calls.
+func discoverTagsGraphQL() ([]*github.RepositoryTag, []*github.RepositoryRelease, error) {
+	var tags []*github.RepositoryTag
+	variables := map[string]interface{}{"owner": Globals.Owner, "repo": Globals.Repo, "cursor": nil}
+	cache := getCommitCache()
+	for {
+		var page graphQLTagsResponse
+		if err := graphQL(tagsQuery, variables, &page); err != nil {
+			return nil, nil, fmt.Errorf("error fetching tags: %w", err)
+		}
+		for _, node := range page.Repository.Refs.Nodes {
+			commit := &node.Target.graphQLCommit
+			if node.Target.Target != nil {
+				commit = node.Target.Target
+			}
+			if commit.Oid == "" {
+				// tags of trees or blobs can't be released
+				continue
+			}
+			tags = append(tags, &github.RepositoryTag{
+				Name:   github.String(node.Name),
+				Commit: &github.Commit{SHA: github.String(commit.Oid)},
+			})
+			info := commitInfo{Date: commit.CommittedDate}
+			if commit.Author.User != nil {
+				info.Author = commit.Author.User.Login
+			}
+			cache.set(commit.Oid, info)
+		}
+		if !page.Repository.Refs.PageInfo.HasNextPage {
+			break
+		}
+		variables["cursor"] = page.Repository.Refs.PageInfo.EndCursor
+	}
+	if err := cache.save(); err != nil {
+		fmt.Printf("Failed to save the commit cache: %s\n", err)
+	}
+
+	var releases []*github.RepositoryRelease
+	variables["cursor"] = nil
+	for {
+		var page graphQLReleasesResponse
+		if err := graphQL(releasesQuery, variables, &page); err != nil {
+			return nil, nil, fmt.Errorf("error fetching releases: %w", err)
+		}
+		for _, node := range page.Repository.Releases.Nodes {
+			release := &github.RepositoryRelease{
+				ID:         github.Int64(node.DatabaseID),
+				TagName:    github.String(node.TagName),
+				Name:       github.String(node.Name),
+				Body:       github.String(node.Description),
+				Prerelease: github.Bool(node.IsPrerelease),
+				Draft:      github.Bool(node.IsDraft),
+			}
+			// releases target the commit they were created at, which is the one of their tag
+			if node.TagCommit != nil {
+				release.TargetCommitish = github.String(node.TagCommit.Oid)
+			}
+			releases = append(releases, release)
+		}
+		if !page.Repository.Releases.PageInfo.HasNextPage {
+			break
+		}
+		variables["cursor"] = page.Repository.Releases.PageInfo.EndCursor
+	}
+	return tags, releases, nil
+}
+
+// discoverTags reads the tags and releases of the source repo through GraphQL when it's
+// the configured tag source, falling back to the REST API when the query fails
+func discoverTags() bool {
+	if Globals.TagSource != GraphQLTagSource {
+		return false
+	}
+	if tagsDiscovered {
+		return true
+	}
+	tags, releases, err := discoverTagsGraphQL()
+	if err != nil {
+		fmt.Printf("Error when discovering tags through GraphQL: %s\nWill fall back to the REST API.\n", err)
+		Globals.TagSource = RESTTagSource
+		return false
+	}
+	fmt.Printf("Discovered %d tag(s) and %d release(s) through GraphQL.\n", len(tags), len(releases))
+	tagsDiscovered, discoveredTags, discoveredReleases = true, tags, releases
+	return true
+}
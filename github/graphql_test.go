@@ -0,0 +1,77 @@
+package gh
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v39/github"
+)
+
+func TestDiscoverTagsGraphQL(t *testing.T) {
+	tagPages := []string{
+		`{"data":{"repository":{"refs":{"pageInfo":{"hasNextPage":true,"endCursor":"c1"},"nodes":[
+			{"name":"1.2.4-rc1","target":{"oid":"bbb","committedDate":"2024-10-02T00:00:00Z","author":{"user":{"login":"alice"}}}},
+			{"name":"1.2.3","target":{"target":{"oid":"aaa","committedDate":"2024-09-01T00:00:00Z","author":{"user":null}}}}
+		]}}}}`,
+		`{"data":{"repository":{"refs":{"pageInfo":{"hasNextPage":false,"endCursor":"c2"},"nodes":[
+			{"name":"tree-tag","target":{}}
+		]}}}}`,
+	}
+	releasePage := `{"data":{"repository":{"releases":{"pageInfo":{"hasNextPage":false},"nodes":[
+		{"databaseId":42,"tagName":"1.2.4-rc1","description":"notes","isPrerelease":true,"isDraft":false,"tagCommit":{"oid":"bbb"}},
+		{"tagName":"1.2.3","isPrerelease":false,"isDraft":false}
+	]}}}}`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/graphql" {
+			t.Errorf("Unexpected request to %s", r.URL.Path)
+		}
+		var body struct {
+			Query     string                 `json:"query"`
+			Variables map[string]interface{} `json:"variables"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		switch {
+		case strings.Contains(body.Query, "releases("):
+			io.WriteString(w, releasePage)
+		case body.Variables["cursor"] == nil:
+			io.WriteString(w, tagPages[0])
+		default:
+			io.WriteString(w, tagPages[1])
+		}
+	}))
+	defer server.Close()
+
+	previous, previousCommits := Globals, commits
+	defer func() { Globals, commits = previous, previousCommits }()
+	client := github.NewClient(nil)
+	client.BaseURL, _ = url.Parse(server.URL + "/api/v3/")
+	Globals = AppContext{Owner: "o", Repo: "r", Ctx: context.Background(), Client: client}
+	commits = loadCommitCache("")
+
+	tags, releases, err := discoverTagsGraphQL()
+	if err != nil {
+		t.Fatalf("Error returned from discoverTagsGraphQL: %v", err)
+	}
+	if len(tags) != 2 || tags[0].GetName() != "1.2.4-rc1" || tags[1].GetCommit().GetSHA() != "aaa" {
+		t.Errorf("Unexpected tags: %v", tags)
+	}
+	if len(releases) != 2 || !releases[0].GetPrerelease() || releases[1].GetTagName() != "1.2.3" {
+		t.Errorf("Unexpected releases: %v", releases)
+	}
+	if releases[0].GetID() != 42 || releases[0].GetTargetCommitish() != "bbb" || releases[0].GetBody() != "notes" {
+		t.Errorf("Expected the ID, target and body of the release but got %v", releases[0])
+	}
+	if info, _ := commits.get("bbb"); info.Author != "alice" || info.Date.Day() != 2 {
+		t.Errorf("Expected the commit cache to be seeded but got %+v", info)
+	}
+	if info, ok := commits.get("aaa"); !ok || info.Author != "" {
+		t.Errorf("Expected the annotated tag's commit to be cached without an author but got %+v", info)
+	}
+}
@@ -0,0 +1,111 @@
+package gh
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const defaultHelmTagPath = "image.tag"
+
+// helmValues bumps the image tag of Helm values
+type helmValues struct {
+	ImageURL string
+	KeyPath  string
+}
+
+func (h helmValues) Bump(content string, newTag string) (string, []string, error) {
+	return bumpHelmValues(content, h.ImageURL, h.KeyPath, newTag)
+}
+
+// bumpHelmValues sets the image tag at a key path (`image.tag` by default) of a values file and
+// returns the updated content along with the previous tags. When the tag sits next to a
+// `repository`, it has to be the image.
+func bumpHelmValues(content string, imageURL string, keyPath string, newTag string) (string, []string, error) {
+	if keyPath == "" {
+		keyPath = defaultHelmTagPath
+	}
+	documents, err := parseYAMLDocuments(content)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid YAML: %w", err)
+	}
+	parentPath, key := "", keyPath
+	if i := strings.LastIndex(keyPath, "."); i != -1 {
+		parentPath, key = keyPath[:i], keyPath[i+1:]
+	}
+	var edits []yamlEdit
+	var previousTags []string
+	for _, document := range documents {
+		if len(document.Content) == 0 {
+			continue
+		}
+		parents := []*yaml.Node{document.Content[0]}
+		if parentPath != "" {
+			if parents, err = resolveYAMLPath(document, parentPath); err != nil {
+				return "", nil, err
+			}
+		}
+		for _, parent := range parents {
+			tag := mappingValue(parent, key)
+			if tag == nil {
+				continue
+			}
+			if tag.Kind != yaml.ScalarNode {
+				return "", nil, fmt.Errorf("the value of %s at line %d is not a tag", keyPath, tag.Line)
+			}
+			if repository := mappingValue(parent, "repository"); repository != nil && imageURL != "" && repository.Value != imageURL {
+				return "", nil, fmt.Errorf("%s at line %d is the tag of %s, not %s", keyPath, tag.Line, repository.Value, imageURL)
+			}
+			previousTags = append(previousTags, tag.Value)
+			edits = append(edits, yamlEdit{node: tag, value: newTag})
+		}
+	}
+	if len(edits) == 0 {
+		return "", nil, fmt.Errorf("no %s found", keyPath)
+	}
+	updated, err := applyYAMLEdits(content, edits)
+	if err != nil {
+		return "", nil, err
+	}
+	return updated, previousTags, nil
+}
+
+// bumpChart sets the appVersion of a Chart.yaml to the new tag and bumps the patch of the chart
+// version, unless the appVersion already is the new tag
+func bumpChart(content string, newTag string) (string, error) {
+	documents, err := parseYAMLDocuments(content)
+	if err != nil {
+		return "", fmt.Errorf("invalid YAML: %w", err)
+	}
+	if len(documents) == 0 || len(documents[0].Content) == 0 {
+		return "", fmt.Errorf("empty chart")
+	}
+	chart := documents[0].Content[0]
+	version := mappingValue(chart, "version")
+	if version == nil {
+		return "", fmt.Errorf("the chart has no version")
+	}
+	appVersion := mappingValue(chart, "appVersion")
+	if appVersion != nil && appVersion.Value == newTag {
+		return content, nil
+	}
+	chartVersion, err := ParseVersion(version.Value)
+	if err != nil {
+		return "", fmt.Errorf("invalid chart version: %w", err)
+	}
+	// the pre-release and build parts of the chart version are kept
+	chartVersion.Patch++
+	edits := []yamlEdit{{node: version, value: chartVersion.String()}}
+	if appVersion != nil {
+		edits = append(edits, yamlEdit{node: appVersion, value: newTag})
+	} else {
+		// add the appVersion right below the version, lined up with it
+		for i := 0; i+1 < len(chart.Content); i += 2 {
+			if chart.Content[i+1] == version {
+				edits = append(edits, yamlEdit{node: version, value: strings.Repeat(" ", chart.Content[i].Column-1) + "appVersion: " + newTag, lineAfter: true})
+			}
+		}
+	}
+	return applyYAMLEdits(content, edits)
+}
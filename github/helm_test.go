@@ -0,0 +1,110 @@
+package gh
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBumpHelmValues(t *testing.T) {
+	testCases := []struct {
+		name         string
+		content      string
+		keyPath      string
+		expected     string
+		previousTags []string
+		wantErr      bool
+	}{
+		{
+			"image.tag",
+			"replicaCount: 2\nimage:\n  repository: ghcr.io/org/app\n  tag: 1.2.3 # staging\n  pullPolicy: IfNotPresent\n",
+			"",
+			"replicaCount: 2\nimage:\n  repository: ghcr.io/org/app\n  tag: 1.2.4-rc1 # staging\n  pullPolicy: IfNotPresent\n",
+			[]string{"1.2.3"}, false,
+		},
+		{
+			"quoted tag",
+			"image:\n  repository: ghcr.io/org/app\n  tag: \"1.2.3\"\n",
+			"",
+			"image:\n  repository: ghcr.io/org/app\n  tag: \"1.2.4-rc1\"\n",
+			[]string{"1.2.3"}, false,
+		},
+		{
+			"configured path",
+			"api:\n  image:\n    tag: 1.2.3\nworker:\n  image:\n    tag: 1.0.0\n",
+			"api.image.tag",
+			"api:\n  image:\n    tag: 1.2.4-rc1\nworker:\n  image:\n    tag: 1.0.0\n",
+			[]string{"1.2.3"}, false,
+		},
+		{
+			"top level key",
+			"appTag: 1.2.3\n",
+			"appTag",
+			"appTag: 1.2.4-rc1\n",
+			[]string{"1.2.3"}, false,
+		},
+		{"tag of another image", "image:\n  repository: ghcr.io/org/worker\n  tag: 1.2.3\n", "", "", nil, true},
+		{"not a scalar", "image:\n  tag:\n    - 1.2.3\n", "", "", nil, true},
+		{"no tag", "image:\n  repository: ghcr.io/org/app\n", "", "", nil, true},
+	}
+
+	for _, tc := range testCases {
+		actual, previousTags, err := bumpHelmValues(tc.content, "ghcr.io/org/app", tc.keyPath, "1.2.4-rc1")
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s: unexpected error %v", tc.name, err)
+			continue
+		}
+		if actual != tc.expected {
+			t.Errorf("%s: expected\n%s\nbut got\n%s", tc.name, tc.expected, actual)
+		}
+		if !reflect.DeepEqual(previousTags, tc.previousTags) {
+			t.Errorf("%s: expected previous tags %v but got %v", tc.name, tc.previousTags, previousTags)
+		}
+	}
+}
+
+func TestBumpChart(t *testing.T) {
+	testCases := []struct {
+		name     string
+		content  string
+		expected string
+		wantErr  bool
+	}{
+		{
+			"app version and chart version",
+			"apiVersion: v2\nname: app\nversion: 0.4.2\nappVersion: \"1.2.3\"\n",
+			"apiVersion: v2\nname: app\nversion: 0.4.3\nappVersion: \"1.2.4-rc1\"\n",
+			false,
+		},
+		{
+			"without an app version",
+			"apiVersion: v2\nname: app\nversion: 0.4.2 # chart\ntype: application\n",
+			"apiVersion: v2\nname: app\nversion: 0.4.3 # chart\nappVersion: 1.2.4-rc1\ntype: application\n",
+			false,
+		},
+		{
+			"already bumped",
+			"name: app\nversion: 0.4.3\nappVersion: 1.2.4-rc1\n",
+			"name: app\nversion: 0.4.3\nappVersion: 1.2.4-rc1\n",
+			false,
+		},
+		{
+			"pre-release chart version",
+			"name: app\nversion: 0.4.2-alpha.1+build.7\nappVersion: 1.2.3\n",
+			"name: app\nversion: 0.4.3-alpha.1+build.7\nappVersion: 1.2.4-rc1\n",
+			false,
+		},
+		{"no version", "name: app\nappVersion: 1.2.3\n", "", true},
+		{"invalid version", "name: app\nversion: latest\n", "", true},
+	}
+
+	for _, tc := range testCases {
+		actual, err := bumpChart(tc.content, "1.2.4-rc1")
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s: unexpected error %v", tc.name, err)
+			continue
+		}
+		if actual != tc.expected {
+			t.Errorf("%s: expected\n%s\nbut got\n%s", tc.name, tc.expected, actual)
+		}
+	}
+}
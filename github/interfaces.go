@@ -10,13 +10,43 @@ type AppContext struct {
 	Owner                    string
 	Repo                     string
 	Branch                   string
+	// the commit at the head of Branch, resolved once at the start of the run
+	HeadSHA                  string
 	UserDefinedOldTag        string
 	DeploymentsRepo          string
-	DeploymentYAMLPath		 string
+	// the monorepo component being released, its tag prefix and the directory it lives in
+	Component                string
+	TagPrefix                string
+	ComponentPath            string
+	// the files of the deployments repo bumped when deploying to staging and production
+	StagingManifests         []ManifestFile
+	ProductionManifests      []ManifestFile
 	WorkflowRetryLimit       int
 	WorkflowRetryWaitSeconds int
 	ConfigImageURL           string
 	IsPrerelease             bool
+	InferVersionChange       bool
+	TagFormat                TagFormat
+	TagCollisionPolicy       TagCollisionPolicy
+	TagDayCutoff             int
+	CacheDir                 string
+	TagSource                TagSource
+	OwnershipPolicy          OwnershipPolicy
+	RCTeam                   []string
+	RCGitHubTeam             string
+	OldTagSource             OldTagSource
+	ReleaseNotesMode         ReleaseNotesMode
+	ChangelogPath            string
+	// commit the entries of each rc to the Unreleased section of the changelog, moving the branch
+	ChangelogRCCommit        bool
+	ReleaseLifecycle         ReleaseLifecycle
+	FailedReleasePolicy      FailedReleasePolicy
+	AnnotatedTags            bool
+	// globs of the files attached to created releases
+	ReleaseAssets            []string
+	ReleaseSummaryAsset      bool
+	// "Name <email>" of the tagger of annotated tags, defaults to the authenticated user
+	Tagger                   string
 	Ctx                      context.Context
 	Client                   *github.Client
 }
@@ -25,7 +55,10 @@ var Globals AppContext
 
 type VersionChangeType string
 const (
-	Minor    VersionChangeType = "minor"
-	Major    VersionChangeType = "major"
-	Breaking VersionChangeType = "breaking"
+	Minor    VersionChangeType = "minor"    // 1.2.3 -> 1.2.4-rc1, 1.2.4-rc1 -> 1.2.4-rc2
+	Major    VersionChangeType = "major"    // 1.2.3 -> 1.3.0-rc1
+	Breaking VersionChangeType = "breaking" // 1.2.3 -> 2.0.0-rc1
 )
+
+// the pre-release label used for release candidates (e.g. `1.2.4-rc1`)
+const defaultPrereleaseLabel = "rc"
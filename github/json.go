@@ -0,0 +1,153 @@
+package gh
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// jsonPointer bumps the string at a JSON pointer (RFC 6901), e.g. `/containerDefinitions/0/image`
+type jsonPointer struct {
+	ImageURL string
+	Pointer  string
+}
+
+// jsonFrame is an object or array the decoder is in, and where in it
+type jsonFrame struct {
+	array   bool
+	index   int
+	key     string
+	wantKey bool
+}
+
+// parseJSONPointer splits a pointer into its unescaped reference tokens
+func parseJSONPointer(pointer string) ([]string, error) {
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q, it must start with /", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+	return tokens, nil
+}
+
+func (f jsonFrame) token() string {
+	if f.array {
+		return strconv.Itoa(f.index)
+	}
+	return f.key
+}
+
+func atJSONPointer(frames []*jsonFrame, tokens []string) bool {
+	if len(frames) != len(tokens) {
+		return false
+	}
+	for i, frame := range frames {
+		if frame.token() != tokens[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// findJSONStrings returns the byte ranges (quotes included) and values of the strings at the
+// pointer. Duplicate keys can make it more than one.
+func findJSONStrings(content string, tokens []string) ([][2]int, []string, error) {
+	decoder := json.NewDecoder(strings.NewReader(content))
+	decoder.UseNumber()
+	var frames []*jsonFrame
+	var ranges [][2]int
+	var values []string
+	// moves past a value of the current object or array
+	next := func() {
+		if len(frames) == 0 {
+			return
+		}
+		if top := frames[len(frames)-1]; top.array {
+			top.index++
+		} else {
+			top.wantKey = true
+		}
+	}
+	for {
+		offset := decoder.InputOffset()
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			if len(frames) > 0 {
+				return nil, nil, errors.New("invalid JSON: unexpected end of input")
+			}
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		if len(frames) > 0 && frames[len(frames)-1].wantKey {
+			if key, ok := token.(string); ok {
+				frames[len(frames)-1].key = key
+				frames[len(frames)-1].wantKey = false
+				continue
+			}
+		}
+		if delim, ok := token.(json.Delim); ok {
+			switch delim {
+			case '{', '[':
+				if atJSONPointer(frames, tokens) {
+					return nil, nil, fmt.Errorf("the value at /%s is not a string", strings.Join(tokens, "/"))
+				}
+				frames = append(frames, &jsonFrame{array: delim == '[', wantKey: delim == '{'})
+			case '}', ']':
+				frames = frames[:len(frames)-1]
+				next()
+			}
+			continue
+		}
+		if atJSONPointer(frames, tokens) {
+			value, ok := token.(string)
+			if !ok {
+				return nil, nil, fmt.Errorf("the value at /%s is not a string", strings.Join(tokens, "/"))
+			}
+			// the offset is before the separators and blanks preceding the string
+			start := offset + int64(strings.IndexByte(content[offset:], '"'))
+			ranges = append(ranges, [2]int{int(start), int(decoder.InputOffset())})
+			values = append(values, value)
+		}
+		next()
+	}
+	return ranges, values, nil
+}
+
+func (j jsonPointer) Bump(content string, newTag string) (string, []string, error) {
+	tokens, err := parseJSONPointer(j.Pointer)
+	if err != nil {
+		return "", nil, err
+	}
+	ranges, values, err := findJSONStrings(content, tokens)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(ranges) == 0 {
+		return "", nil, fmt.Errorf("nothing found at %s", j.Pointer)
+	}
+	previousTags := make([]string, len(values))
+	replacements := make([]string, len(values))
+	for i, value := range values {
+		bumped, previousTag, err := bumpTagValue(value, j.ImageURL, newTag)
+		if err != nil {
+			return "", nil, fmt.Errorf("the value at %s: %w", j.Pointer, err)
+		}
+		encoded, err := json.Marshal(bumped)
+		if err != nil {
+			return "", nil, err
+		}
+		previousTags[i], replacements[i] = previousTag, string(encoded)
+	}
+	// the strings are in file order, replace from the end so that earlier offsets stay valid
+	for i := len(ranges) - 1; i >= 0; i-- {
+		content = content[:ranges[i][0]] + replacements[i] + content[ranges[i][1]:]
+	}
+	return content, previousTags, nil
+}
@@ -0,0 +1,43 @@
+package gh
+
+import "testing"
+
+func TestJSONPointerGolden(t *testing.T) {
+	testCases := []struct {
+		input        string
+		golden       string
+		manifest     ManifestFile
+		previousTags []string
+	}{
+		{"taskdef.json", "taskdef-image.json.golden", ManifestFile{Type: JSONManifest, ImageURL: "ghcr.io/org/app", KeyPath: "/containerDefinitions/0/image"}, []string{"1.2.3"}},
+		{"taskdef.json", "taskdef-environment.json.golden", ManifestFile{Type: JSONManifest, ImageURL: "ghcr.io/org/app", KeyPath: "/containerDefinitions/0/environment/0/value"}, []string{"1.2.3"}},
+		{"taskdef.json", "taskdef-tag.json.golden", ManifestFile{Type: JSONManifest, KeyPath: "/tags/app~1version"}, []string{"1.2.3"}},
+	}
+
+	for _, tc := range testCases {
+		checkGolden(t, tc.input, tc.golden, tc.manifest, tc.previousTags)
+	}
+}
+
+func TestJSONPointer(t *testing.T) {
+	testCases := []struct {
+		name    string
+		content string
+		pointer string
+	}{
+		{"another image", `{"image": "ghcr.io/org/worker:1.2.3"}`, "/image"},
+		{"not a string", `{"image": {"tag": "1.2.3"}}`, "/image"},
+		{"a number", `{"version": 3}`, "/version"},
+		{"missing", `{"image": "ghcr.io/org/app:1.2.3"}`, "/containers/0/image"},
+		{"out of range", `{"images": ["ghcr.io/org/app:1.2.3"]}`, "/images/1"},
+		{"invalid pointer", `{"image": "ghcr.io/org/app:1.2.3"}`, "image"},
+		{"invalid JSON", `{"image": "ghcr.io/org/app:1.2.3"`, "/image"},
+	}
+
+	for _, tc := range testCases {
+		_, _, err := jsonPointer{ImageURL: "ghcr.io/org/app", Pointer: tc.pointer}.Bump(tc.content, "1.2.4-rc1")
+		if err == nil {
+			t.Errorf("%s: expected an error", tc.name)
+		}
+	}
+}
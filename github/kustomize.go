@@ -0,0 +1,86 @@
+package gh
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// kustomizeImage bumps the `images` entries of a kustomization
+type kustomizeImage struct {
+	ImageURL string
+}
+
+func (k kustomizeImage) Bump(content string, newTag string) (string, []string, error) {
+	return bumpKustomizeImage(content, k.ImageURL, newTag)
+}
+
+// bumpKustomizeImage sets the `newTag` of the `images` entries of a kustomization whose `newName`
+// (or `name` when they have none) is the image, and returns the updated content along with their
+// previous tags. Entries pinned to a `digest` are switched to the tag, with the digest as the
+// previous tag.
+func bumpKustomizeImage(content string, imageURL string, newTag string) (string, []string, error) {
+	documents, err := parseYAMLDocuments(content)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid YAML: %w", err)
+	}
+	var edits []yamlEdit
+	var previousTags []string
+	for _, document := range documents {
+		entries, err := resolveYAMLPath(document, "images[*]")
+		if err != nil {
+			return "", nil, err
+		}
+		for _, entry := range entries {
+			name, newName := mappingValue(entry, "name"), mappingValue(entry, "newName")
+			// the name is the image of the base, renamed to newName
+			if newName != nil && newName.Value != imageURL || newName == nil && (name == nil || name.Value != imageURL) {
+				continue
+			}
+			tag := mappingValue(entry, "newTag")
+			if digest := mappingValue(entry, "digest"); digest != nil {
+				if entry.Style&yaml.FlowStyle != 0 {
+					return "", nil, fmt.Errorf("can't replace the digest of the image entry of %s at line %d", imageURL, entry.Line)
+				}
+				previousTags = append(previousTags, digest.Value)
+				if tag != nil {
+					// the digest takes precedence over the tag, drop it
+					edits = append(edits, yamlEdit{node: tag, value: newTag}, yamlEdit{node: digest, removeLine: true})
+					continue
+				}
+				for i := 0; i+1 < len(entry.Content); i += 2 {
+					if entry.Content[i+1] == digest {
+						edits = append(edits, yamlEdit{node: entry.Content[i], value: "newTag"}, yamlEdit{node: digest, value: newTag})
+					}
+				}
+				continue
+			}
+			if tag != nil {
+				previousTags = append(previousTags, tag.Value)
+				edits = append(edits, yamlEdit{node: tag, value: newTag})
+				continue
+			}
+			if entry.Style&yaml.FlowStyle != 0 || name == nil {
+				return "", nil, fmt.Errorf("can't add a newTag to the image entry of %s at line %d", imageURL, entry.Line)
+			}
+			// add the tag right below the name, lined up with it
+			key := entry.Content[0]
+			for i := 0; i < len(entry.Content); i += 2 {
+				if entry.Content[i+1] == name {
+					key = entry.Content[i]
+				}
+			}
+			previousTags = append(previousTags, "")
+			edits = append(edits, yamlEdit{node: name, value: strings.Repeat(" ", key.Column-1) + "newTag: " + newTag, lineAfter: true})
+		}
+	}
+	if len(edits) == 0 {
+		return "", nil, fmt.Errorf("no images entry named %s found", imageURL)
+	}
+	updated, err := applyYAMLEdits(content, edits)
+	if err != nil {
+		return "", nil, err
+	}
+	return updated, previousTags, nil
+}
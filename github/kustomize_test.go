@@ -0,0 +1,65 @@
+package gh
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBumpKustomizeImage(t *testing.T) {
+	testCases := []struct {
+		name         string
+		content      string
+		expected     string
+		previousTags []string
+		wantErr      bool
+	}{
+		{
+			"by name",
+			"resources:\n  - ../../base\nimages:\n  - name: ghcr.io/org/app\n    newTag: 1.2.3 # staging\n  - name: ghcr.io/org/worker\n    newTag: 1.2.3\n",
+			"resources:\n  - ../../base\nimages:\n  - name: ghcr.io/org/app\n    newTag: 1.2.4-rc1 # staging\n  - name: ghcr.io/org/worker\n    newTag: 1.2.3\n",
+			[]string{"1.2.3"}, false,
+		},
+		{
+			"by new name",
+			"images:\n- name: app\n  newName: ghcr.io/org/app\n  newTag: \"1.2.3\"\n",
+			"images:\n- name: app\n  newName: ghcr.io/org/app\n  newTag: \"1.2.4-rc1\"\n",
+			[]string{"1.2.3"}, false,
+		},
+		{
+			"without a tag yet",
+			"images:\n  - name: ghcr.io/org/app # from base\n    newName: ghcr.io/org/app\nnamespace: staging\n",
+			"images:\n  - name: ghcr.io/org/app # from base\n    newTag: 1.2.4-rc1\n    newName: ghcr.io/org/app\nnamespace: staging\n",
+			[]string{""}, false,
+		},
+		{
+			"pinned to a digest",
+			"images:\n  - name: ghcr.io/org/app\n    digest: sha256:abc123 # pinned\n",
+			"images:\n  - name: ghcr.io/org/app\n    newTag: 1.2.4-rc1 # pinned\n",
+			[]string{"sha256:abc123"}, false,
+		},
+		{
+			"pinned to a digest along with a tag",
+			"images:\n  - name: ghcr.io/org/app\n    newTag: 1.2.3\n    digest: sha256:abc123\nnamespace: staging\n",
+			"images:\n  - name: ghcr.io/org/app\n    newTag: 1.2.4-rc1\nnamespace: staging\n",
+			[]string{"sha256:abc123"}, false,
+		},
+		{"renamed to another image", "images:\n  - name: ghcr.io/org/app\n    newName: ghcr.io/org/app-debug\n    newTag: 1.2.3\n", "", nil, true},
+		{"flow style without a tag", "images: [{name: ghcr.io/org/app}]\n", "", nil, true},
+		{"unknown image", "images:\n  - name: ghcr.io/org/worker\n    newTag: 1.2.3\n", "", nil, true},
+		{"no images", "resources:\n  - ../../base\n", "", nil, true},
+	}
+
+	for _, tc := range testCases {
+		actual, previousTags, err := bumpKustomizeImage(tc.content, "ghcr.io/org/app", "1.2.4-rc1")
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s: unexpected error %v", tc.name, err)
+			continue
+		}
+		if actual != tc.expected {
+			t.Errorf("%s: expected\n%s\nbut got\n%s", tc.name, tc.expected, actual)
+		}
+		if !reflect.DeepEqual(previousTags, tc.previousTags) {
+			t.Errorf("%s: expected previous tags %v but got %v", tc.name, tc.previousTags, previousTags)
+		}
+	}
+}
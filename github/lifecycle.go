@@ -0,0 +1,126 @@
+package gh
+
+import (
+	"fmt"
+
+	"github.com/google/go-github/v39/github"
+)
+
+// ReleaseLifecycle decides when a release is shown as released
+type ReleaseLifecycle string
+
+const (
+	// publish the release right away
+	PublishLifecycle ReleaseLifecycle = "publish"
+	// keep the release as a draft until its build and deployment succeeded
+	DraftLifecycle ReleaseLifecycle = "draft"
+	// flag final releases as prereleases until their build and deployment succeeded
+	PrereleaseLifecycle ReleaseLifecycle = "prerelease"
+)
+
+// ParseReleaseLifecycle validates a lifecycle from the config, defaulting to publishing right away
+func ParseReleaseLifecycle(lifecycle string) (ReleaseLifecycle, error) {
+	switch ReleaseLifecycle(lifecycle) {
+	case "":
+		return PublishLifecycle, nil
+	case PublishLifecycle, DraftLifecycle, PrereleaseLifecycle:
+		return ReleaseLifecycle(lifecycle), nil
+	}
+	return "", fmt.Errorf("unknown release lifecycle %q (expected %q, %q or %q)", lifecycle, PublishLifecycle, DraftLifecycle, PrereleaseLifecycle)
+}
+
+// draftReleases reports whether releases are created as drafts
+func draftReleases() bool {
+	return Globals.ReleaseLifecycle == DraftLifecycle
+}
+
+// heldAsPrerelease returns the prerelease flag a release is created with: with the prerelease
+// lifecycle, final releases are flagged until they're deployed. Rcs are prereleases either way.
+func heldAsPrerelease(prerelease bool) bool {
+	return prerelease || Globals.ReleaseLifecycle == PrereleaseLifecycle
+}
+
+// FailedReleasePolicy decides what happens to a release whose build or deployment failed
+type FailedReleasePolicy string
+
+const (
+	// delete the draft release along with its tag
+	DeleteFailedRelease FailedReleasePolicy = "delete"
+	// keep the draft and note why it was never published
+	AnnotateFailedRelease FailedReleasePolicy = "annotate"
+)
+
+// ParseFailedReleasePolicy validates a policy from the config, defaulting to annotating the draft
+func ParseFailedReleasePolicy(policy string) (FailedReleasePolicy, error) {
+	switch FailedReleasePolicy(policy) {
+	case "":
+		return AnnotateFailedRelease, nil
+	case DeleteFailedRelease, AnnotateFailedRelease:
+		return FailedReleasePolicy(policy), nil
+	}
+	return "", fmt.Errorf("unknown failed release policy %q (expected %q or %q)", policy, DeleteFailedRelease, AnnotateFailedRelease)
+}
+
+// FinalizeRelease publishes a draft release, or clears the prerelease flag of a final release
+// held as a prerelease, once its build and deployment succeeded. Otherwise the release is deleted
+// or annotated with the reason, depending on the failed release policy.
+func FinalizeRelease(release *github.RepositoryRelease, succeeded bool, reason string) {
+	if release == nil {
+		return
+	}
+	tag := release.GetTagName()
+	if succeeded {
+		edit := &github.RepositoryRelease{}
+		if release.GetDraft() {
+			edit.Draft = github.Bool(false)
+		}
+		if version, err := Globals.TagFormat.Parse(tag); err == nil && release.GetPrerelease() && !version.IsPrerelease() && Globals.ReleaseLifecycle == PrereleaseLifecycle {
+			edit.Prerelease = github.Bool(false)
+		}
+		if edit.Draft == nil && edit.Prerelease == nil {
+			return
+		}
+		_, _, err := Globals.Client.Repositories.EditRelease(Globals.Ctx, Globals.Owner, Globals.Repo, release.GetID(), edit)
+		if err != nil {
+			fmt.Printf("Failed to publish release %s: %s\n", tag, err)
+			return
+		}
+		fmt.Printf("Release %s was published.\n", tag)
+		return
+	}
+
+	kind := "Release"
+	if release.GetDraft() {
+		kind = "Draft release"
+	}
+	switch Globals.FailedReleasePolicy {
+	case DeleteFailedRelease:
+		if _, err := Globals.Client.Repositories.DeleteRelease(Globals.Ctx, Globals.Owner, Globals.Repo, release.GetID()); err != nil {
+			fmt.Printf("Failed to delete release %s: %s\n", tag, err)
+			return
+		}
+		if _, err := Globals.Client.Git.DeleteRef(Globals.Ctx, Globals.Owner, Globals.Repo, "tags/"+tag); err != nil {
+			fmt.Printf("Failed to delete tag %s: %s\n", tag, err)
+			return
+		}
+		fmt.Printf("%s %s and its tag were deleted: %s.\n", kind, tag, reason)
+	default:
+		note := "Not published by autodeployer"
+		if !release.GetDraft() {
+			note = "Not deployed by autodeployer"
+		}
+		body := fmt.Sprintf("> **%s**: %s.\n\n%s", note, reason, release.GetBody())
+		_, _, err := Globals.Client.Repositories.EditRelease(Globals.Ctx, Globals.Owner, Globals.Repo, release.GetID(), &github.RepositoryRelease{
+			Body: github.String(body),
+		})
+		if err != nil {
+			fmt.Printf("Failed to annotate release %s: %s\n", tag, err)
+			return
+		}
+		if release.GetDraft() {
+			fmt.Printf("Draft release %s was left unpublished: %s.\n", tag, reason)
+		} else {
+			fmt.Printf("Warning: release %s was left published although %s.\n", tag, reason)
+		}
+	}
+}
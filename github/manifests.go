@@ -0,0 +1,236 @@
+package gh
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v39/github"
+)
+
+// ManifestType says how the image tag is stored in a manifest
+type ManifestType string
+
+const (
+	// `<image>:<tag>` references anywhere in a YAML file (e.g. Kubernetes manifests)
+	ImageManifest ManifestType = "image"
+	// the `newTag` of an `images` entry of a kustomization
+	KustomizeManifest ManifestType = "kustomize"
+	// a bare tag at a key path of Helm values, `image.tag` by default
+	HelmManifest ManifestType = "helm"
+	// the string at a JSON pointer (e.g. `/containerDefinitions/0/image` of an ECS task definition)
+	JSONManifest ManifestType = "json"
+	// the string at a dotted key of a TOML file
+	TOMLManifest ManifestType = "toml"
+	// the value of a variable of a .env file
+	DotenvManifest ManifestType = "dotenv"
+	// the `tag` group of every match of a regular expression, for any other format
+	RegexManifest ManifestType = "regex"
+)
+
+var manifestTypes = []ManifestType{ImageManifest, KustomizeManifest, HelmManifest, JSONManifest, TOMLManifest, DotenvManifest, RegexManifest}
+
+// ParseManifestType validates a manifest type from the config, defaulting to image references
+func ParseManifestType(manifestType string) (ManifestType, error) {
+	if manifestType == "" {
+		return ImageManifest, nil
+	}
+	names := make([]string, len(manifestTypes))
+	for i, known := range manifestTypes {
+		if ManifestType(manifestType) == known {
+			return known, nil
+		}
+		names[i] = string(known)
+	}
+	return "", fmt.Errorf("unknown manifest type %q (expected one of %s)", manifestType, strings.Join(names, ", "))
+}
+
+// ManifestUpdater rewrites the tag of an image in the content of a manifest
+type ManifestUpdater interface {
+	// Bump points the image at the new tag and returns the updated content along with the tags
+	// the image had before. It fails when the image can't be found.
+	Bump(content string, newTag string) (string, []string, error)
+}
+
+// ManifestFile is a file of the deployments repo and the rule finding the image bumped in it
+type ManifestFile struct {
+	Path     string
+	Type     ManifestType
+	ImageURL string
+	// where the image is in the file: a key path or a container name (anywhere when both are empty).
+	// For JSON, TOML and .env files, the pointer, key or variable holding the image or its tag.
+	KeyPath   string
+	Container string
+	// the Chart.yaml whose appVersion follows the image tag of Helm values (none when empty)
+	ChartPath string
+	// the regular expression of regex manifests, with a `tag` group
+	Pattern string
+}
+
+// paths lists the files of the deployments repo the manifest changes
+func (m ManifestFile) paths() []string {
+	if m.Type == HelmManifest && m.ChartPath != "" {
+		return []string{m.Path, m.ChartPath}
+	}
+	return []string{m.Path}
+}
+
+func (m ManifestFile) rule() yamlImageRule {
+	return yamlImageRule{ImageURL: m.ImageURL, KeyPath: m.KeyPath, Container: m.Container}
+}
+
+// updater returns the updater of the manifest type, configured for the manifest
+func (m ManifestFile) updater() (ManifestUpdater, error) {
+	switch m.Type {
+	case ImageManifest, "":
+		return m.rule(), nil
+	case KustomizeManifest:
+		return kustomizeImage{ImageURL: m.ImageURL}, nil
+	case HelmManifest:
+		return helmValues{ImageURL: m.ImageURL, KeyPath: m.KeyPath}, nil
+	case RegexManifest:
+		rule, err := newRegexRule(m.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		return rule, nil
+	}
+	if m.KeyPath == "" {
+		return nil, fmt.Errorf("%s manifests need an `image-path`", m.Type)
+	}
+	switch m.Type {
+	case JSONManifest:
+		return jsonPointer{ImageURL: m.ImageURL, Pointer: m.KeyPath}, nil
+	case TOMLManifest:
+		return tomlKey{ImageURL: m.ImageURL, Key: m.KeyPath}, nil
+	case DotenvManifest:
+		return dotenvVariable{ImageURL: m.ImageURL, Name: m.KeyPath}, nil
+	}
+	return nil, fmt.Errorf("unknown manifest type %q", m.Type)
+}
+
+// Validate checks that the manifest has what its type needs, before anything is released
+func (m ManifestFile) Validate() error {
+	_, err := m.updater()
+	return err
+}
+
+// bump points the image of the manifest at the new tag and returns the updated content along
+// with the tags the image had before
+func (m ManifestFile) bump(content string, newTag string) (string, []string, error) {
+	updater, err := m.updater()
+	if err != nil {
+		return "", nil, err
+	}
+	return updater.Bump(content, newTag)
+}
+
+var bareTag = regexp.MustCompile(`^[\w][\w.-]{0,127}$`)
+
+// bumpTagValue points a value holding the image at the new tag and returns it along with the
+// previous tag. A `<image>:<tag>` reference keeps its image, a bare tag is replaced.
+func bumpTagValue(value string, imageURL string, newTag string) (string, string, error) {
+	if imageURL != "" {
+		if match := imageReference(imageURL).FindStringSubmatch(value); match != nil {
+			return imageURL + ":" + newTag, match[1], nil
+		}
+	}
+	if bareTag.MatchString(value) {
+		return newTag, value, nil
+	}
+	if imageURL != "" {
+		return "", "", fmt.Errorf("%q is neither a tag nor a reference to %s", value, imageURL)
+	}
+	return "", "", fmt.Errorf("%q is not a tag", value)
+}
+
+// bumpManifestContents applies the manifests to the current contents of their files (path ->
+// content) and returns the files that changed. Several manifests can point at the same file.
+func bumpManifestContents(manifests []ManifestFile, contents map[string]string, oldTag string, newTag string) (map[string]string, error) {
+	updated := map[string]string{}
+	for path, content := range contents {
+		updated[path] = content
+	}
+	for _, manifest := range manifests {
+		content, previousTags, err := manifest.bump(updated[manifest.Path], imageTag(newTag))
+		if err != nil {
+			return nil, fmt.Errorf("error bumping the image in %s: %w", manifest.Path, err)
+		}
+		for _, previousTag := range previousTags {
+			if previousTag != imageTag(oldTag) && previousTag != imageTag(newTag) {
+				fmt.Printf("Warning: %s referenced %s:%s rather than the old tag %s\n", manifest.Path, manifest.ImageURL, previousTag, imageTag(oldTag))
+			}
+		}
+		updated[manifest.Path] = content
+		if manifest.Type == HelmManifest && manifest.ChartPath != "" {
+			chart, err := bumpChart(updated[manifest.ChartPath], imageTag(newTag))
+			if err != nil {
+				return nil, fmt.Errorf("error bumping the chart %s: %w", manifest.ChartPath, err)
+			}
+			updated[manifest.ChartPath] = chart
+		}
+	}
+	for path, content := range updated {
+		if contents[path] == content {
+			delete(updated, path)
+		}
+	}
+	return updated, nil
+}
+
+// bumpManifests points the images of the manifests at the new tag and lands every change in a
+// single commit on the deployment branch. It returns the head of the branch: the commit it made,
+// or the existing head when every manifest was already up to date.
+func bumpManifests(manifests []ManifestFile, oldTag string, newTag string, branchRef string) (string, error) {
+	if len(manifests) == 0 {
+		return "", errors.New("no manifest configured")
+	}
+	ref, _, err := Globals.Client.Git.GetRef(Globals.Ctx, Globals.Owner, Globals.DeploymentsRepo, branchRef)
+	if err != nil {
+		return "", fmt.Errorf("error fetching %s: %w", branchRef, err)
+	}
+	parentSHA := ref.GetObject().GetSHA()
+	contents := map[string]string{}
+	for _, manifest := range manifests {
+		for _, path := range manifest.paths() {
+			if _, ok := contents[path]; ok {
+				continue
+			}
+			_, content, err := getFileContent(Globals.DeploymentsRepo, path, parentSHA)
+			if err != nil {
+				return "", fmt.Errorf("error reading %s: %w", path, err)
+			}
+			contents[path] = content
+		}
+	}
+	updated, err := bumpManifestContents(manifests, contents, oldTag, newTag)
+	if err != nil {
+		return "", err
+	}
+	if len(updated) == 0 {
+		fmt.Printf("Every manifest already references %s.\n", imageTag(newTag))
+		return parentSHA, nil
+	}
+	commitSHA, err := createCommit(Globals.DeploymentsRepo, parentSHA, updated, fmt.Sprintf("Image tag bumped to %s using autodeployer", newTag))
+	if err != nil {
+		return "", fmt.Errorf("error committing the manifests: %w", err)
+	}
+	_, _, err = Globals.Client.Git.UpdateRef(Globals.Ctx, Globals.Owner, Globals.DeploymentsRepo, &github.Reference{
+		Ref:    github.String(branchRef),
+		Object: &github.GitObject{SHA: github.String(commitSHA)},
+	}, false)
+	if err != nil {
+		return "", fmt.Errorf("error updating %s: %w", branchRef, err)
+	}
+	paths := make([]string, 0, len(updated))
+	for path := range updated {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		fmt.Printf("Successfully bumped image version in %s!\n", path)
+	}
+	return commitSHA, nil
+}
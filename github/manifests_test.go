@@ -0,0 +1,103 @@
+package gh
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestBumpManifestContents(t *testing.T) {
+	contents := map[string]string{
+		"app.yaml":     "# app\nimage: ghcr.io/org/app:1.2.3\nworker:\n  image: ghcr.io/org/worker:1.2.3\n",
+		"cron.yaml":    "image: ghcr.io/org/app:1.2.3 # cron\n",
+		"current.yaml": "image: ghcr.io/org/app:1.2.4-rc1\n",
+		"values.yaml":  "image:\n  repository: ghcr.io/org/app\n  tag: 1.2.3\n",
+		"Chart.yaml":   "name: app\nversion: 0.1.0\nappVersion: 1.2.3\n",
+	}
+	testCases := []struct {
+		name      string
+		manifests []ManifestFile
+		expected  map[string]string
+		wantErr   bool
+	}{
+		{
+			"several files and images",
+			[]ManifestFile{
+				{Path: "app.yaml", ImageURL: "ghcr.io/org/app", KeyPath: "image"},
+				{Path: "app.yaml", ImageURL: "ghcr.io/org/worker"},
+				{Path: "cron.yaml", ImageURL: "ghcr.io/org/app"},
+			},
+			map[string]string{
+				"app.yaml":  "# app\nimage: ghcr.io/org/app:1.2.4-rc1\nworker:\n  image: ghcr.io/org/worker:1.2.4-rc1\n",
+				"cron.yaml": "image: ghcr.io/org/app:1.2.4-rc1 # cron\n",
+			},
+			false,
+		},
+		{
+			"files already bumped are left out",
+			[]ManifestFile{{Path: "current.yaml", ImageURL: "ghcr.io/org/app"}, {Path: "cron.yaml", ImageURL: "ghcr.io/org/app"}},
+			map[string]string{"cron.yaml": "image: ghcr.io/org/app:1.2.4-rc1 # cron\n"},
+			false,
+		},
+		{
+			"helm values along with their chart",
+			[]ManifestFile{{Path: "values.yaml", Type: HelmManifest, ImageURL: "ghcr.io/org/app", ChartPath: "Chart.yaml"}},
+			map[string]string{
+				"values.yaml": "image:\n  repository: ghcr.io/org/app\n  tag: 1.2.4-rc1\n",
+				"Chart.yaml":  "name: app\nversion: 0.1.1\nappVersion: 1.2.4-rc1\n",
+			},
+			false,
+		},
+		{
+			"a file without a match fails the whole bump",
+			[]ManifestFile{{Path: "cron.yaml", ImageURL: "ghcr.io/org/app"}, {Path: "cron.yaml", ImageURL: "ghcr.io/org/worker"}},
+			nil,
+			true,
+		},
+	}
+
+	for _, tc := range testCases {
+		actual, err := bumpManifestContents(tc.manifests, contents, "1.2.3", "1.2.4-rc1")
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s: unexpected error %v", tc.name, err)
+			continue
+		}
+		if err == nil && !reflect.DeepEqual(actual, tc.expected) {
+			t.Errorf("%s: expected %v but got %v", tc.name, tc.expected, actual)
+		}
+	}
+}
+
+var update = flag.Bool("update", false, "rewrite the golden files of testdata/manifests")
+
+// bumps a file of testdata/manifests and compares the result with its golden file
+func checkGolden(t *testing.T, input string, golden string, manifest ManifestFile, previousTags []string) {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join("testdata", "manifests", input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, actualPreviousTags, err := manifest.bump(string(content), "1.2.4-rc1")
+	if err != nil {
+		t.Errorf("%s: unexpected error %v", golden, err)
+		return
+	}
+	goldenPath := filepath.Join("testdata", "manifests", golden)
+	if *update {
+		if err := os.WriteFile(goldenPath, []byte(actual), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual != string(expected) {
+		t.Errorf("%s: expected\n%s\nbut got\n%s", golden, expected, actual)
+	}
+	if !reflect.DeepEqual(actualPreviousTags, previousTags) {
+		t.Errorf("%s: expected previous tags %v but got %v", golden, previousTags, actualPreviousTags)
+	}
+}
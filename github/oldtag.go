@@ -0,0 +1,53 @@
+package gh
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/google/go-github/v39/github"
+)
+
+// sortTagsByPrecedence orders tags from the highest version to the lowest (so `1.2.4` >
+// `1.2.4-rc10` > `1.2.4-rc9`), dropping the ones that don't follow the tag format
+func sortTagsByPrecedence(tags []string, format TagFormat) []string {
+	type parsedTag struct {
+		name    string
+		version Version
+	}
+	var parsed []parsedTag
+	for _, tag := range tags {
+		version, err := format.Parse(tag)
+		if err != nil {
+			continue
+		}
+		parsed = append(parsed, parsedTag{tag, version})
+	}
+	sort.SliceStable(parsed, func(i, j int) bool {
+		return parsed[i].version.Compare(parsed[j].version) > 0
+	})
+	sorted := make([]string, len(parsed))
+	for i, tag := range parsed {
+		sorted[i] = tag.name
+	}
+	return sorted
+}
+
+// selectOldTag picks the tag the new one is bumped from, and says why. The rc line at the head
+// of the branch is continued from its highest version. Without a tag at the head (which passed
+// the filters), a new line starts from the highest official release.
+func selectOldTag(tags []*github.RepositoryTag, branch string, headSHA string, officialReleases []string, format TagFormat) (string, string, error) {
+	var atHead []string
+	for _, tag := range tags {
+		if tag.GetCommit().GetSHA() == headSHA {
+			atHead = append(atHead, tag.GetName())
+		}
+	}
+	if sorted := sortTagsByPrecedence(atHead, format); len(sorted) > 0 {
+		return sorted[0], fmt.Sprintf("highest of %d tag(s) at the head of %s (%.7s)", len(sorted), branch, headSHA), nil
+	}
+	sorted := sortTagsByPrecedence(officialReleases, format)
+	if len(sorted) == 0 {
+		return "", "", fmt.Errorf("no tag at the head of %s and no official release to start from", branch)
+	}
+	return sorted[0], fmt.Sprintf("no tag at the head of %s (%.7s), highest of %d official release(s)", branch, headSHA, len(sorted)), nil
+}
@@ -0,0 +1,82 @@
+package gh
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/go-github/v39/github"
+)
+
+func TestSortTagsByPrecedence(t *testing.T) {
+	betaFormat, err := NewTagFormat("v{major}.{minor}.{patch}", "-beta.{n}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		tags     []string
+		format   TagFormat
+		expected []string
+	}{
+		{[]string{"1.2.4-rc9", "1.2.4", "1.2.4-rc10", "1.10.0", "1.9.0", "not-a-tag"}, TagFormat{}, []string{"1.10.0", "1.9.0", "1.2.4", "1.2.4-rc10", "1.2.4-rc9"}},
+		{[]string{"v1.0.0-beta.2", "v1.0.0-beta.10", "1.0.0", "v0.9.9"}, betaFormat, []string{"v1.0.0-beta.10", "v1.0.0-beta.2", "v0.9.9"}},
+		{nil, TagFormat{}, []string{}},
+	}
+
+	for _, tc := range testCases {
+		actual := sortTagsByPrecedence(tc.tags, tc.format)
+		if !reflect.DeepEqual(actual, tc.expected) {
+			t.Errorf("sortTagsByPrecedence(%v): expected %v but got %v", tc.tags, tc.expected, actual)
+		}
+	}
+}
+
+func TestSelectOldTag(t *testing.T) {
+	tag := func(name string, sha string) *github.RepositoryTag {
+		return &github.RepositoryTag{Name: github.String(name), Commit: &github.Commit{SHA: github.String(sha)}}
+	}
+	testCases := []struct {
+		name             string
+		tags             []*github.RepositoryTag
+		officialReleases []string
+		expected         string
+		reason           string
+		wantErr          bool
+	}{
+		{
+			"highest rc at the head",
+			[]*github.RepositoryTag{tag("1.2.4-rc9", "head"), tag("1.3.0-rc1", "other"), tag("1.2.4-rc10", "head"), tag("1.2.4-rc2", "head")},
+			[]string{"1.2.3"},
+			"1.2.4-rc10", "highest of 3 tag(s) at the head of main (head)", false,
+		},
+		{
+			"promoted rc at the head",
+			[]*github.RepositoryTag{tag("1.2.4-rc3", "head"), tag("1.2.4", "head")},
+			[]string{"1.2.4", "1.2.3"},
+			"1.2.4", "highest of 2 tag(s) at the head of main (head)", false,
+		},
+		{
+			"highest official release without tags at the head",
+			[]*github.RepositoryTag{tag("1.3.0-rc1", "other")},
+			[]string{"1.2.9", "1.10.0", "1.2.10"},
+			"1.10.0", "no tag at the head of main (head), highest of 3 official release(s)", false,
+		},
+		{
+			"tags at the head that don't follow the format",
+			[]*github.RepositoryTag{tag("deploy-2024", "head")},
+			[]string{"1.2.3"},
+			"1.2.3", "no tag at the head of main (head), highest of 1 official release(s)", false,
+		},
+		{"nothing to start from", nil, nil, "", "", true},
+	}
+
+	for _, tc := range testCases {
+		actual, reason, err := selectOldTag(tc.tags, "main", "head", tc.officialReleases, TagFormat{})
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s: unexpected error %v", tc.name, err)
+			continue
+		}
+		if actual != tc.expected || reason != tc.reason {
+			t.Errorf("%s: expected %s (%s) but got %s (%s)", tc.name, tc.expected, tc.reason, actual, reason)
+		}
+	}
+}
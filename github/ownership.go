@@ -0,0 +1,109 @@
+package gh
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v39/github"
+)
+
+// OwnershipPolicy decides whose rc tags can be continued
+type OwnershipPolicy string
+
+const (
+	// only continue rc lines started by the authenticated user
+	MineOnly OwnershipPolicy = "mine"
+	// continue the rc line of the branch no matter who started it
+	AnyoneOnBranch OwnershipPolicy = "anyone"
+	// continue rc lines started by anyone in the `rc_team` list of the config
+	TeamList OwnershipPolicy = "team"
+	// continue rc lines started by members of the `rc_github_team` GitHub team (org/slug)
+	GitHubTeam OwnershipPolicy = "github-team"
+)
+
+// ParseOwnershipPolicy validates a policy from the config or the CLI, defaulting to mine-only
+func ParseOwnershipPolicy(policy string) (OwnershipPolicy, error) {
+	switch OwnershipPolicy(policy) {
+	case "":
+		return MineOnly, nil
+	case MineOnly, AnyoneOnBranch, TeamList, GitHubTeam:
+		return OwnershipPolicy(policy), nil
+	}
+	return "", fmt.Errorf("unknown rc ownership policy %q (expected %q, %q, %q or %q)", policy, MineOnly, AnyoneOnBranch, TeamList, GitHubTeam)
+}
+
+// getAllowedAuthors returns the logins whose tags can be continued under the ownership
+// policy, or nil when tags by anyone are allowed
+func getAllowedAuthors() (map[string]bool, error) {
+	if Globals.OwnershipPolicy == AnyoneOnBranch {
+		return nil, nil
+	}
+	username, err := getUsername()
+	if err != nil {
+		return nil, fmt.Errorf("error fetching username: %w", err)
+	}
+	authors := map[string]bool{username: true}
+	switch Globals.OwnershipPolicy {
+	case TeamList:
+		for _, member := range Globals.RCTeam {
+			authors[member] = true
+		}
+	case GitHubTeam:
+		members, err := getGitHubTeamMembers(Globals.RCGitHubTeam)
+		if err != nil {
+			return nil, err
+		}
+		for _, member := range members {
+			authors[member] = true
+		}
+	}
+	return authors, nil
+}
+
+// getGitHubTeamMembers lists the logins of the members of an `org/team-slug` team
+func getGitHubTeamMembers(team string) ([]string, error) {
+	org, slug, found := strings.Cut(team, "/")
+	if !found || org == "" || slug == "" {
+		return nil, fmt.Errorf("invalid GitHub team %q (expected org/team-slug)", team)
+	}
+	var members []string
+	options := &github.TeamListTeamMembersOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		users, resp, err := Globals.Client.Teams.ListTeamMembersBySlug(Globals.Ctx, org, slug, options)
+		if err != nil {
+			return nil, fmt.Errorf("error fetching members of %s: %w", team, err)
+		}
+		for _, user := range users {
+			members = append(members, user.GetLogin())
+		}
+		if resp.NextPage == 0 {
+			return members, nil
+		}
+		options.Page = resp.NextPage
+	}
+}
+
+// filterTagsByAuthors keeps the tags whose commit was authored by one of the given logins
+func filterTagsByAuthors(tags []*github.RepositoryTag, commits map[string]commitInfo, authors map[string]bool) []*github.RepositoryTag {
+	var filteredTags []*github.RepositoryTag
+	for _, tag := range tags {
+		if authors[commits[tag.GetCommit().GetSHA()].Author] {
+			filteredTags = append(filteredTags, tag)
+		}
+	}
+	return filteredTags
+}
+
+// describeOwnershipPolicy prints the policy along with the authors it allows
+func describeOwnershipPolicy(authors map[string]bool) string {
+	if authors == nil {
+		return string(Globals.OwnershipPolicy)
+	}
+	logins := make([]string, 0, len(authors))
+	for login := range authors {
+		logins = append(logins, login)
+	}
+	sort.Strings(logins)
+	return fmt.Sprintf("%s (%s)", Globals.OwnershipPolicy, strings.Join(logins, ", "))
+}
@@ -0,0 +1,114 @@
+package gh
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/google/go-github/v39/github"
+)
+
+// getTagCommitSHA resolves a tag to the SHA of the commit it points at, peeling annotated tags
+func getTagCommitSHA(tag string) (string, error) {
+	ref, _, err := Globals.Client.Git.GetRef(Globals.Ctx, Globals.Owner, Globals.Repo, "refs/tags/"+tag)
+	if err != nil {
+		return "", fmt.Errorf("error fetching tag %s: %w", tag, err)
+	}
+	object := ref.GetObject()
+	for object.GetType() == "tag" {
+		annotatedTag, _, err := Globals.Client.Git.GetTag(Globals.Ctx, Globals.Owner, Globals.Repo, object.GetSHA())
+		if err != nil {
+			return "", fmt.Errorf("error fetching annotated tag %s: %w", tag, err)
+		}
+		object = annotatedTag.GetObject()
+	}
+	if object.GetType() != "commit" {
+		return "", fmt.Errorf("tag %s points at a %s, not a commit", tag, object.GetType())
+	}
+	return object.GetSHA(), nil
+}
+
+// getReleaseBranch reads the branch an rc was released from, as recorded in its release
+func getReleaseBranch(rcTag string) (string, error) {
+	release, _, err := Globals.Client.Repositories.GetReleaseByTag(Globals.Ctx, Globals.Owner, Globals.Repo, rcTag)
+	if err != nil {
+		return "", fmt.Errorf("error fetching the release of %s: %w", rcTag, err)
+	}
+	branch := releaseBranch(release.GetBody())
+	if branch == "" {
+		return "", fmt.Errorf("the release of %s doesn't record its branch", rcTag)
+	}
+	return branch, nil
+}
+
+// PromoteRelease creates the final release (e.g. `1.2.4`) at the exact commit of a release
+// candidate (e.g. `1.2.4-rc3`)
+func PromoteRelease(rcTag string) *github.RepositoryRelease {
+	fmt.Printf("[1/5] Promoting %s to a final release...\n", rcTag)
+	rcVersion, err := Globals.TagFormat.Parse(rcTag)
+	if err != nil {
+		fmt.Printf("Failed to parse %s: %s\n", rcTag, err)
+		os.Exit(1)
+	}
+	if !rcVersion.IsPrerelease() {
+		fmt.Printf("%s is not a release candidate\n", rcTag)
+		os.Exit(1)
+	}
+	finalTag := Globals.TagFormat.Render(rcVersion.Core())
+
+	taken, err := getTakenTags()
+	if err != nil {
+		fmt.Printf("Failed to check existing tags: %s\n", err)
+		os.Exit(1)
+	}
+	if taken[finalTag] {
+		fmt.Printf("%s has already been released\n", finalTag)
+		os.Exit(1)
+	}
+	commitSHA, err := getTagCommitSHA(rcTag)
+	if err != nil {
+		fmt.Printf("Failed to resolve %s: %s\n", rcTag, err)
+		os.Exit(1)
+	}
+	if Globals.ChangelogPath != "" {
+		branch, err := getReleaseBranch(rcTag)
+		if err != nil {
+			fmt.Printf("Failed to find the branch of %s: %s\n", rcTag, err)
+			os.Exit(1)
+		}
+		if err := releaseChangelog(branch, commitSHA, finalTag); err != nil {
+			fmt.Printf("Failed to update the changelog: %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	fmt.Printf("[2/5] Creating release %s at %.7s...\n", finalTag, commitSHA)
+	release := &github.RepositoryRelease{
+		TagName:         github.String(finalTag),
+		TargetCommitish: github.String(commitSHA),
+		Name:            github.String(finalTag),
+		Body:            github.String(fmt.Sprintf("Release promoted from %s using autodeployer", rcTag)),
+		Draft:           github.Bool(draftReleases()),
+		Prerelease:      github.Bool(heldAsPrerelease(false)),
+	}
+	if needsTagUpfront() {
+		if err := createTag(finalTag, commitSHA, tagMessage(rcTag, finalTag, "Promoted from "+rcTag+" using autodeployer")); err != nil {
+			fmt.Printf("Failed to create tag %s: %s\n", finalTag, err)
+			os.Exit(1)
+		}
+	}
+	createdRelease, _, err := Globals.Client.Repositories.CreateRelease(Globals.Ctx, Globals.Owner, Globals.Repo, release)
+	if err != nil {
+		fmt.Printf("Failed to create release: %s\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Release %s was successfully created.\n", finalTag)
+	return createdRelease
+}
+
+// GetDeployedProductionTag reads the tag currently deployed to production
+func GetDeployedProductionTag() (string, error) {
+	if len(Globals.ProductionManifests) == 0 {
+		return "", fmt.Errorf("no production manifest configured for %s", Globals.Repo)
+	}
+	return getDeployedTag(Globals.ProductionManifests[0])
+}
@@ -0,0 +1,89 @@
+package gh
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v39/github"
+)
+
+func TestPromoteReleaseWithChangelog(t *testing.T) {
+	changelog := base64.StdEncoding.EncodeToString([]byte("# Changelog\n\n## [Unreleased]\n\n### Added\n\n- feat: add search\n"))
+	var blob, updatedRef, releaseTarget string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := r.Method + " " + strings.TrimPrefix(r.URL.Path, "/api/v3/repos/o/r/")
+		switch route {
+		case "GET tags":
+			io.WriteString(w, `[{"name":"1.2.4-rc3","commit":{"sha":"rc"}}]`)
+		case "GET releases":
+			io.WriteString(w, `[{"id":1,"tag_name":"1.2.4-rc3"}]`)
+		case "GET git/ref/tags/1.2.4-rc3":
+			io.WriteString(w, `{"ref":"refs/tags/1.2.4-rc3","object":{"type":"commit","sha":"rc"}}`)
+		case "GET releases/tags/1.2.4-rc3":
+			io.WriteString(w, `{"id":1,"tag_name":"1.2.4-rc3","body":"notes\n\n<!-- autodeployer-branch: feature/search -->\n"}`)
+		case "GET git/ref/heads/feature/search":
+			io.WriteString(w, `{"ref":"refs/heads/feature/search","object":{"type":"commit","sha":"head"}}`)
+		case "GET contents/CHANGELOG.md":
+			fmt.Fprintf(w, `{"type":"file","encoding":"base64","content":%q}`, changelog)
+		case "GET git/commits/head":
+			io.WriteString(w, `{"sha":"head","tree":{"sha":"tree"}}`)
+		case "GET git/trees/tree":
+			io.WriteString(w, `{"sha":"tree","tree":[{"path":"CHANGELOG.md","mode":"100644","type":"blob","sha":"old"}]}`)
+		case "POST git/blobs":
+			var body github.Blob
+			json.NewDecoder(r.Body).Decode(&body)
+			blob = body.GetContent()
+			io.WriteString(w, `{"sha":"blob"}`)
+		case "POST git/trees":
+			io.WriteString(w, `{"sha":"newtree"}`)
+		case "POST git/commits":
+			io.WriteString(w, `{"sha":"changelog"}`)
+		case "PATCH git/refs/heads/feature/search":
+			var body struct {
+				SHA   string `json:"sha"`
+				Force bool   `json:"force"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			if body.Force {
+				t.Error("Expected the branch to only be fast-forwarded")
+			}
+			updatedRef = body.SHA
+			io.WriteString(w, `{"ref":"refs/heads/feature/search","object":{"sha":"changelog"}}`)
+		case "POST releases":
+			var body github.RepositoryRelease
+			json.NewDecoder(r.Body).Decode(&body)
+			releaseTarget = body.GetTargetCommitish()
+			io.WriteString(w, `{"id":2,"tag_name":"1.2.4","target_commitish":"rc"}`)
+		default:
+			t.Errorf("Unexpected request %s", route)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	previous := Globals
+	defer func() { Globals = previous }()
+	client := github.NewClient(nil)
+	client.BaseURL, _ = url.Parse(server.URL + "/api/v3/")
+	// no branch: promote only gets a tag
+	Globals = AppContext{Owner: "o", Repo: "r", Ctx: context.Background(), Client: client, ChangelogPath: "CHANGELOG.md"}
+
+	release := PromoteRelease("1.2.4-rc3")
+	if release.GetTagName() != "1.2.4" || releaseTarget != "rc" {
+		t.Errorf("Expected 1.2.4 to be released at the rc commit but got %s at %s", release.GetTagName(), releaseTarget)
+	}
+	if !strings.Contains(blob, "## [1.2.4] - ") || !strings.Contains(blob, "- feat: add search") {
+		t.Errorf("Expected the changelog to release the entries of the rc but got:\n%s", blob)
+	}
+	if updatedRef != "changelog" {
+		t.Errorf("Expected the branch of the rc to point at the changelog commit but got %q", updatedRef)
+	}
+}
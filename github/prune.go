@@ -0,0 +1,314 @@
+package gh
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v39/github"
+)
+
+const (
+	// deletions made before pausing, GitHub asks for mutative requests to be spread out
+	pruneBatchSize = 20
+	pruneBatchWait = 2 * time.Second
+	// the remaining core rate limit below which pruning waits for the reset
+	pruneRateLimitReserve = 50
+	pruneRetries          = 3
+)
+
+var commitSHAPattern = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// PruneOptions are the filters of the prune command. The age and author filters narrow the
+// candidates down, the branch and superseded filters are reasons to prune them.
+type PruneOptions struct {
+	OlderThanDays int
+	// the branch the rc was cut from was merged into the default branch or deleted
+	BranchGone bool
+	// a final release at or above the version of the rc exists
+	Superseded bool
+	// prune the rcs of everyone rather than only the ones of the authenticated user
+	AllAuthors bool
+}
+
+// PrunableTag is an rc tag (and its release, when it has one) selected by the prune filters
+type PrunableTag struct {
+	Tag       string
+	Version   Version
+	Author    string
+	Date      time.Time
+	ReleaseID int64
+	// the branch the rc was released from, when its release recorded it
+	Branch     string
+	BranchGone bool
+	Reasons    []string
+}
+
+// inPruneScope reports whether an rc passes the age and author filters
+func inPruneScope(tag PrunableTag, options PruneOptions, username string, now time.Time) bool {
+	if !options.AllAuthors && tag.Author != username {
+		return false
+	}
+	return options.OlderThanDays <= 0 || !tag.Date.After(now.AddDate(0, 0, -options.OlderThanDays))
+}
+
+// liveLineHeads finds the highest rc of each line that is still going: no final release at or
+// above it and its branch isn't gone. The next rc of the line is cut from it.
+func liveLineHeads(candidates []PrunableTag, finals []Version) map[string]bool {
+	heads := map[string]PrunableTag{}
+	for _, tag := range candidates {
+		line := tag.Version.Core().String()
+		if head, ok := heads[line]; !ok || tag.Version.Compare(head.Version) > 0 {
+			heads[line] = tag
+		}
+	}
+	live := map[string]bool{}
+	for _, head := range heads {
+		superseded := false
+		for _, final := range finals {
+			if final.Compare(head.Version.Core()) >= 0 {
+				superseded = true
+				break
+			}
+		}
+		if !superseded && !head.BranchGone {
+			live[head.Tag] = true
+		}
+	}
+	return live
+}
+
+// selectPrunableTags keeps the rcs in scope that have a reason to be pruned, oldest version first.
+// With only an age filter, being old enough is the reason. The deployed rcs and the highest rc of
+// each live line are never pruned.
+func selectPrunableTags(candidates []PrunableTag, finals []Version, deployed map[string]bool, format TagFormat, options PruneOptions, username string, now time.Time) []PrunableTag {
+	live := liveLineHeads(candidates, finals)
+	var selected []PrunableTag
+	for _, tag := range candidates {
+		if !tag.Version.IsPrerelease() || !inPruneScope(tag, options, username, now) || deployed[tag.Tag] || live[tag.Tag] {
+			continue
+		}
+		var reasons []string
+		if options.Superseded {
+			for _, final := range finals {
+				if final.Compare(tag.Version.Core()) >= 0 {
+					reasons = append(reasons, "superseded by "+format.Render(final))
+					break
+				}
+			}
+		}
+		if options.BranchGone && tag.BranchGone {
+			reasons = append(reasons, "branch merged or deleted")
+		}
+		if !options.Superseded && !options.BranchGone {
+			reasons = append(reasons, fmt.Sprintf("older than %d days", options.OlderThanDays))
+		}
+		if len(reasons) == 0 {
+			continue
+		}
+		tag.Reasons = reasons
+		selected = append(selected, tag)
+	}
+	sort.SliceStable(selected, func(i, j int) bool {
+		return selected[i].Version.Compare(selected[j].Version) < 0
+	})
+	return selected
+}
+
+// FindPrunableTags lists the rc tags of the repo that match the prune filters
+func FindPrunableTags(options PruneOptions) ([]PrunableTag, error) {
+	if options.OlderThanDays <= 0 && !options.BranchGone && !options.Superseded {
+		return nil, errors.New("refusing to prune every rc, pass -older-than, -branch-gone or -superseded")
+	}
+	tags, err := listAllTags(Globals.Repo)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching tags: %w", err)
+	}
+	tags = filterTagsByFormat(tags, Globals.TagFormat)
+	commits, err := resolveTagCommits(tags)
+	if err != nil {
+		return nil, err
+	}
+	// release IDs and bodies only come from the REST API
+	releases := map[string]*github.RepositoryRelease{}
+	err = forEachReleaseREST(Globals.Repo, func(release *github.RepositoryRelease) bool {
+		releases[release.GetTagName()] = release
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error fetching releases: %w", err)
+	}
+	// the rcs staging is running
+	deployed := map[string]bool{}
+	for _, manifest := range Globals.StagingManifests {
+		tag, err := getDeployedTag(manifest)
+		if err != nil {
+			return nil, fmt.Errorf("error reading the deployed rc, needed to keep it: %w", err)
+		}
+		deployed[tag] = true
+	}
+	username, err := getUsername()
+	if err != nil {
+		return nil, fmt.Errorf("error fetching username: %w", err)
+	}
+
+	var candidates []PrunableTag
+	var finals []Version
+	now := time.Now()
+	for _, tag := range tags {
+		version, err := Globals.TagFormat.Parse(tag.GetName())
+		if err != nil {
+			continue
+		}
+		if !version.IsPrerelease() {
+			finals = append(finals, version)
+			continue
+		}
+		commit := commits[tag.GetCommit().GetSHA()]
+		candidate := PrunableTag{Tag: tag.GetName(), Version: version, Author: commit.Author, Date: commit.Date}
+		if release, ok := releases[tag.GetName()]; ok {
+			candidate.ReleaseID = release.GetID()
+			candidate.Branch = releaseBranch(release.GetBody())
+		}
+		if options.BranchGone && inPruneScope(candidate, options, username, now) {
+			if candidate.BranchGone, err = isBranchGone(candidate.Branch, tag.GetCommit().GetSHA()); err != nil {
+				return nil, err
+			}
+		}
+		candidates = append(candidates, candidate)
+	}
+	return selectPrunableTags(candidates, finals, deployed, Globals.TagFormat, options, username, now), nil
+}
+
+// the default branch and the state of the branches looked up while pruning
+var (
+	defaultBranch string
+	branchExists  = map[string]bool{}
+)
+
+// isBranchGone reports whether the branch an rc was released from no longer exists (it was
+// deleted, e.g. after being squash merged), or whether its commit is part of the default branch
+func isBranchGone(branch string, commitSHA string) (bool, error) {
+	if branch != "" {
+		exists, ok := branchExists[branch]
+		if !ok {
+			err := withRateLimit(func() (*github.Response, error) {
+				_, resp, err := Globals.Client.Repositories.GetBranch(Globals.Ctx, Globals.Owner, Globals.Repo, branch, true)
+				return resp, err
+			})
+			if err != nil && !isNotFound(err) {
+				return false, fmt.Errorf("error fetching branch %s: %w", branch, err)
+			}
+			exists = err == nil
+			branchExists[branch] = exists
+		}
+		if !exists {
+			return true, nil
+		}
+	}
+	if defaultBranch == "" {
+		var repository *github.Repository
+		err := withRateLimit(func() (*github.Response, error) {
+			var resp *github.Response
+			var err error
+			repository, resp, err = Globals.Client.Repositories.Get(Globals.Ctx, Globals.Owner, Globals.Repo)
+			return resp, err
+		})
+		if err != nil {
+			return false, fmt.Errorf("error fetching the default branch: %w", err)
+		}
+		defaultBranch = repository.GetDefaultBranch()
+	}
+	var comparison *github.CommitsComparison
+	err := withRateLimit(func() (*github.Response, error) {
+		var resp *github.Response
+		var err error
+		comparison, resp, err = Globals.Client.Repositories.CompareCommits(Globals.Ctx, Globals.Owner, Globals.Repo, defaultBranch, commitSHA, &github.ListOptions{PerPage: 1})
+		return resp, err
+	})
+	if err != nil {
+		return false, fmt.Errorf("error comparing %.7s with %s: %w", commitSHA, defaultBranch, err)
+	}
+	// the rc commit is already part of the default branch
+	return comparison.GetStatus() == "behind" || comparison.GetStatus() == "identical", nil
+}
+
+// PrintPrunableTags lists the selected rcs and why they are pruned
+func PrintPrunableTags(tags []PrunableTag) {
+	for _, tag := range tags {
+		release := "no release"
+		if tag.ReleaseID != 0 {
+			release = "release"
+		}
+		fmt.Printf("  %-20s %-10s by %-20s %s (%s)\n", tag.Tag, tag.Date.Format("2006-01-02"), tag.Author, release, strings.Join(tag.Reasons, ", "))
+	}
+}
+
+// DeletePrunableTags deletes the releases and tags of the selected rcs in batches, waiting for
+// the rate limit to reset when it runs low. It stops at the first deletion that keeps failing.
+func DeletePrunableTags(tags []PrunableTag) error {
+	for i, tag := range tags {
+		if i > 0 && i%pruneBatchSize == 0 {
+			fmt.Printf("Deleted %d/%d rc(s), pausing before the next batch...\n", i, len(tags))
+			time.Sleep(pruneBatchWait)
+		}
+		if tag.ReleaseID != 0 {
+			err := withRateLimit(func() (*github.Response, error) {
+				return Globals.Client.Repositories.DeleteRelease(Globals.Ctx, Globals.Owner, Globals.Repo, tag.ReleaseID)
+			})
+			if err != nil && !isNotFound(err) {
+				return fmt.Errorf("error deleting the release of %s: %w", tag.Tag, err)
+			}
+		}
+		err := withRateLimit(func() (*github.Response, error) {
+			return Globals.Client.Git.DeleteRef(Globals.Ctx, Globals.Owner, Globals.Repo, "tags/"+tag.Tag)
+		})
+		if err != nil && !isNotFound(err) {
+			return fmt.Errorf("error deleting tag %s: %w", tag.Tag, err)
+		}
+		fmt.Printf("Deleted %s\n", tag.Tag)
+	}
+	return nil
+}
+
+// withRateLimit runs an API call, sleeping through primary and secondary rate limits and
+// ahead of the reset when few calls are left
+func withRateLimit(call func() (*github.Response, error)) error {
+	var err error
+	for attempt := 0; attempt < pruneRetries; attempt++ {
+		var resp *github.Response
+		resp, err = call()
+		var rateLimitErr *github.RateLimitError
+		var abuseErr *github.AbuseRateLimitError
+		switch {
+		case errors.As(err, &rateLimitErr):
+			waitUntil(rateLimitErr.Rate.Reset.Time)
+			continue
+		case errors.As(err, &abuseErr):
+			wait := time.Minute
+			if abuseErr.RetryAfter != nil {
+				wait = *abuseErr.RetryAfter
+			}
+			fmt.Printf("Hit the secondary rate limit, waiting %s...\n", wait)
+			time.Sleep(wait)
+			continue
+		}
+		if resp != nil && resp.Rate.Limit > 0 && resp.Rate.Remaining < pruneRateLimitReserve {
+			waitUntil(resp.Rate.Reset.Time)
+		}
+		return err
+	}
+	return err
+}
+
+func waitUntil(reset time.Time) {
+	wait := time.Until(reset) + time.Second
+	if wait <= 0 {
+		return
+	}
+	fmt.Printf("Rate limit is running low, waiting %s for it to reset...\n", wait.Round(time.Second))
+	time.Sleep(wait)
+}
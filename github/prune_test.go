@@ -0,0 +1,61 @@
+package gh
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSelectPrunableTags(t *testing.T) {
+	now := time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC)
+	rc := func(tag string, author string, daysAgo int, branchGone bool) PrunableTag {
+		version, err := ParseVersion(tag)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return PrunableTag{Tag: tag, Version: version, Author: author, Date: now.AddDate(0, 0, -daysAgo), BranchGone: branchGone}
+	}
+	candidates := []PrunableTag{
+		rc("1.3.0-rc2", "me", 2, false),
+		rc("1.2.4-rc10", "me", 40, false),
+		rc("1.2.4-rc9", "me", 45, true),
+		rc("1.3.0-rc1", "someone", 60, true),
+		rc("1.1.0-rc1", "someone", 90, false),
+		// the head of a live line
+		rc("1.4.0-rc1", "me", 40, false),
+	}
+	finals := []Version{{Major: 1, Minor: 2, Patch: 4}}
+
+	testCases := []struct {
+		name     string
+		options  PruneOptions
+		deployed string
+		expected []string
+		reasons  [][]string
+	}{
+		{"age only", PruneOptions{OlderThanDays: 30}, "", []string{"1.2.4-rc9", "1.2.4-rc10"}, [][]string{{"older than 30 days"}, {"older than 30 days"}}},
+		{"superseded", PruneOptions{Superseded: true}, "", []string{"1.2.4-rc9", "1.2.4-rc10"}, [][]string{{"superseded by 1.2.4"}, {"superseded by 1.2.4"}}},
+		{"superseded, everyone", PruneOptions{Superseded: true, AllAuthors: true}, "", []string{"1.1.0-rc1", "1.2.4-rc9", "1.2.4-rc10"}, [][]string{{"superseded by 1.2.4"}, {"superseded by 1.2.4"}, {"superseded by 1.2.4"}}},
+		{"branch gone", PruneOptions{BranchGone: true, AllAuthors: true}, "", []string{"1.2.4-rc9", "1.3.0-rc1"}, [][]string{{"branch merged or deleted"}, {"branch merged or deleted"}}},
+		{"both reasons", PruneOptions{BranchGone: true, Superseded: true}, "", []string{"1.2.4-rc9", "1.2.4-rc10"}, [][]string{{"superseded by 1.2.4", "branch merged or deleted"}, {"superseded by 1.2.4"}}},
+		{"old and branch gone", PruneOptions{OlderThanDays: 50, BranchGone: true, AllAuthors: true}, "", []string{"1.3.0-rc1"}, [][]string{{"branch merged or deleted"}}},
+		{"deployed", PruneOptions{OlderThanDays: 30}, "1.2.4-rc9", []string{"1.2.4-rc10"}, [][]string{{"older than 30 days"}}},
+	}
+
+	for _, tc := range testCases {
+		selected := selectPrunableTags(candidates, finals, map[string]bool{tc.deployed: true}, TagFormat{}, tc.options, "me", now)
+		var actual []string
+		var reasons [][]string
+		for _, tag := range selected {
+			actual = append(actual, tag.Tag)
+			reasons = append(reasons, tag.Reasons)
+		}
+		if !reflect.DeepEqual(actual, tc.expected) {
+			t.Errorf("%s: expected %v but got %v", tc.name, tc.expected, actual)
+			continue
+		}
+		if !reflect.DeepEqual(reasons, tc.reasons) {
+			t.Errorf("%s: expected reasons %v but got %v", tc.name, tc.reasons, reasons)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package gh
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// regexRule bumps the `tag` group of every match of a regular expression, for the formats no
+// other manifest type understands (e.g. `(?m)^app_image\s*=\s*"ghcr.io/org/app:(?P<tag>[^"]+)"`)
+type regexRule struct {
+	pattern *regexp.Regexp
+	group   int
+}
+
+func newRegexRule(pattern string) (regexRule, error) {
+	if pattern == "" {
+		return regexRule{}, errors.New("regex manifests need a `pattern`")
+	}
+	compiled, err := regexp.Compile(pattern)
+	if err != nil {
+		return regexRule{}, fmt.Errorf("invalid pattern: %w", err)
+	}
+	group := compiled.SubexpIndex("tag")
+	if group == -1 {
+		return regexRule{}, fmt.Errorf("the pattern %q has no `(?P<tag>...)` group", pattern)
+	}
+	return regexRule{pattern: compiled, group: group}, nil
+}
+
+func (r regexRule) Bump(content string, newTag string) (string, []string, error) {
+	matches := r.pattern.FindAllStringSubmatchIndex(content, -1)
+	var previousTags []string
+	updated := ""
+	last := 0
+	for _, match := range matches {
+		start, end := match[2*r.group], match[2*r.group+1]
+		if start == -1 {
+			continue
+		}
+		previousTags = append(previousTags, content[start:end])
+		updated += content[last:start] + newTag
+		last = end
+	}
+	if len(previousTags) == 0 {
+		return "", nil, fmt.Errorf("no match of %s found", r.pattern)
+	}
+	return updated + content[last:], previousTags, nil
+}
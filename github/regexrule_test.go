@@ -0,0 +1,36 @@
+package gh
+
+import "testing"
+
+func TestRegexRuleGolden(t *testing.T) {
+	manifest := ManifestFile{Type: RegexManifest, Pattern: `(?m)^(?:app|cron)\.image=ghcr\.io/org/app:(?P<tag>\S+)$`}
+	checkGolden(t, "values.properties", "values.properties.golden", manifest, []string{"1.2.3", "1.2.3"})
+
+	// .tfvars files are HCL, which the toml type can't read
+	manifest = ManifestFile{Type: RegexManifest, Pattern: `(?m)^\s*image\s*=\s*"ghcr\.io/org/app:(?P<tag>[^"]+)"`}
+	checkGolden(t, "terraform.tfvars", "terraform.tfvars.golden", manifest, []string{"1.2.3"})
+}
+
+func TestNewRegexRule(t *testing.T) {
+	testCases := []struct {
+		name    string
+		pattern string
+		wantErr bool
+	}{
+		{"tag group", `image: app:(?P<tag>\S+)`, false},
+		{"no pattern", "", true},
+		{"no tag group", `image: app:(\S+)`, true},
+		{"invalid", `image: app:(?P<tag>\S+`, true},
+	}
+
+	for _, tc := range testCases {
+		_, err := newRegexRule(tc.pattern)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s: unexpected error %v", tc.name, err)
+		}
+	}
+	rule, _ := newRegexRule(`image: app:(?P<tag>\S+)`)
+	if _, _, err := rule.Bump("image: worker:1.2.3\n", "1.2.4-rc1"); err == nil {
+		t.Errorf("expected an error when nothing matches")
+	}
+}
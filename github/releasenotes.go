@@ -0,0 +1,344 @@
+package gh
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v39/github"
+)
+
+// ReleaseNotesMode decides how the body of created releases is written
+type ReleaseNotesMode string
+
+const (
+	// built by autodeployer from the commits and merged pull requests since the old tag
+	GeneratedReleaseNotes ReleaseNotesMode = "generated"
+	// built by GitHub's generate-release-notes endpoint
+	GitHubReleaseNotes ReleaseNotesMode = "github"
+	// the fixed "Release created using autodeployer" body
+	PlainReleaseNotes ReleaseNotesMode = "plain"
+)
+
+const plainReleaseBody = "Release created using autodeployer"
+
+// the branch an rc was released from, recorded in its release body (hidden when rendered) so
+// that prune can tell when the branch is gone
+var releaseBranchMarker = regexp.MustCompile(`(?m)^<!-- autodeployer-branch: (\S+) -->$`)
+
+// withReleaseBranch records the branch of the run in a release body
+func withReleaseBranch(body string) string {
+	return fmt.Sprintf("%s\n\n<!-- autodeployer-branch: %s -->\n", strings.TrimRight(body, "\n"), Globals.Branch)
+}
+
+// releaseBranch reads the branch recorded in a release body, if any
+func releaseBranch(body string) string {
+	if match := releaseBranchMarker.FindStringSubmatch(body); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// ParseReleaseNotesMode validates a release notes mode from the config, defaulting to generated notes
+func ParseReleaseNotesMode(mode string) (ReleaseNotesMode, error) {
+	switch ReleaseNotesMode(mode) {
+	case "":
+		return GeneratedReleaseNotes, nil
+	case GeneratedReleaseNotes, GitHubReleaseNotes, PlainReleaseNotes:
+		return ReleaseNotesMode(mode), nil
+	}
+	return "", fmt.Errorf("unknown release notes mode %q (expected %q, %q or %q)", mode, GeneratedReleaseNotes, GitHubReleaseNotes, PlainReleaseNotes)
+}
+
+// releaseNoteEntry is a merged pull request, or a commit that didn't go through one
+type releaseNoteEntry struct {
+	Title  string
+	Body   string
+	Author string
+	PR     int
+	SHA    string
+	Labels []string
+}
+
+// the sections of the release notes, in the order they are rendered
+var releaseNoteSections = []string{"⚠️ Breaking changes", "🚀 Features", "🐛 Fixes", "🧰 Other changes"}
+
+// labels that put an entry in a section regardless of its title
+var releaseNoteLabels = map[string]int{
+	"breaking":        0,
+	"breaking-change": 0,
+	"breaking change": 0,
+	"feature":         1,
+	"enhancement":     1,
+	"bug":             2,
+	"fix":             2,
+	"bugfix":          2,
+}
+
+// section picks where an entry goes, preferring labels over the conventional commit type
+func (e releaseNoteEntry) section() int {
+	for _, label := range e.Labels {
+		if section, ok := releaseNoteLabels[strings.ToLower(label)]; ok {
+			return section
+		}
+	}
+	commitType, breaking := parseConventionalCommit(e.Title + "\n\n" + e.Body)
+	switch {
+	case breaking:
+		return 0
+	case commitType == "feat":
+		return 1
+	case commitType == "fix":
+		return 2
+	}
+	return 3
+}
+
+// renderReleaseNotes lays out the entries grouped by section, crediting their authors
+func renderReleaseNotes(oldTag string, newTag string, compareURL string, entries []releaseNoteEntry) string {
+	var notes strings.Builder
+	fmt.Fprintf(&notes, "## What's changed since %s\n", oldTag)
+	if len(entries) == 0 {
+		notes.WriteString("\nNo changes.\n")
+	}
+	grouped := make([][]releaseNoteEntry, len(releaseNoteSections))
+	authors := map[string]bool{}
+	for _, entry := range entries {
+		section := entry.section()
+		grouped[section] = append(grouped[section], entry)
+		if entry.Author != "" {
+			authors[entry.Author] = true
+		}
+	}
+	for i, section := range releaseNoteSections {
+		if len(grouped[i]) == 0 {
+			continue
+		}
+		fmt.Fprintf(&notes, "\n### %s\n\n", section)
+		for _, entry := range grouped[i] {
+			notes.WriteString("- " + entry.Title)
+			if entry.Author != "" {
+				notes.WriteString(" by @" + entry.Author)
+			}
+			if entry.PR != 0 {
+				fmt.Fprintf(&notes, " in #%d", entry.PR)
+			} else {
+				fmt.Fprintf(&notes, " in %.7s", entry.SHA)
+			}
+			notes.WriteString("\n")
+		}
+	}
+	if len(authors) > 0 {
+		logins := make([]string, 0, len(authors))
+		for login := range authors {
+			logins = append(logins, "@"+login)
+		}
+		sort.Strings(logins)
+		fmt.Fprintf(&notes, "\n**Contributors**: %s\n", strings.Join(logins, ", "))
+	}
+	fmt.Fprintf(&notes, "\n**Full changelog**: %s\n\n_%s was created using autodeployer_\n", compareURL, newTag)
+	return notes.String()
+}
+
+// pull requests looked up per commit at most this many at a time through GraphQL
+const pullRequestBatchSize = 50
+
+// pullRequestInfo is the merged pull request a commit went through
+type pullRequestInfo struct {
+	Number int
+	Title  string
+	Body   string
+	Author string
+	Labels []string
+}
+
+type graphQLPullRequest struct {
+	Number   int     `json:"number"`
+	Title    string  `json:"title"`
+	Body     string  `json:"body"`
+	MergedAt *string `json:"mergedAt"`
+	Author   *struct {
+		Login string `json:"login"`
+	} `json:"author"`
+	Labels struct {
+		Nodes []struct {
+			Name string `json:"name"`
+		} `json:"nodes"`
+	} `json:"labels"`
+}
+
+// the merged pull request of the commits looked up so far (nil for commits without one). The
+// changelog and the release notes read the same range, so it's only looked up once per run.
+var commitPullRequests = map[string]*pullRequestInfo{}
+
+// getCommitPullRequests finds the merged pull request of each commit, with one GraphQL query
+// per batch of commits, falling back to one rate limited REST call per commit
+func getCommitPullRequests(shas []string) (map[string]*pullRequestInfo, error) {
+	var missing []string
+	for _, sha := range shas {
+		if _, ok := commitPullRequests[sha]; !ok && commitSHAPattern.MatchString(sha) {
+			missing = append(missing, sha)
+		}
+	}
+	for start := 0; start < len(missing); start += pullRequestBatchSize {
+		batch := missing[start:min(start+pullRequestBatchSize, len(missing))]
+		if err := fetchPullRequestsGraphQL(batch); err != nil {
+			fmt.Printf("Error when looking up pull requests through GraphQL: %s\nWill fall back to the REST API.\n", err)
+			if err := fetchPullRequestsREST(missing[start:]); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+	return commitPullRequests, nil
+}
+
+func fetchPullRequestsGraphQL(shas []string) error {
+	var query strings.Builder
+	query.WriteString("query($owner: String!, $repo: String!) {\n  repository(owner: $owner, name: $repo) {\n")
+	for i, sha := range shas {
+		// the SHAs are validated hex, safe to inline
+		fmt.Fprintf(&query, "    c%d: object(oid: %q) { ... on Commit { associatedPullRequests(first: 5) { nodes { number title body mergedAt author { login } labels(first: 20) { nodes { name } } } } } }\n", i, sha)
+	}
+	query.WriteString("  }\n}")
+	var data struct {
+		Repository map[string]*struct {
+			AssociatedPullRequests struct {
+				Nodes []graphQLPullRequest `json:"nodes"`
+			} `json:"associatedPullRequests"`
+		} `json:"repository"`
+	}
+	if err := graphQL(query.String(), map[string]interface{}{"owner": Globals.Owner, "repo": Globals.Repo}, &data); err != nil {
+		return err
+	}
+	for i, sha := range shas {
+		commitPullRequests[sha] = nil
+		commit := data.Repository[fmt.Sprintf("c%d", i)]
+		if commit == nil {
+			continue
+		}
+		for _, pr := range commit.AssociatedPullRequests.Nodes {
+			if pr.MergedAt == nil {
+				continue
+			}
+			info := &pullRequestInfo{Number: pr.Number, Title: pr.Title, Body: pr.Body}
+			if pr.Author != nil {
+				info.Author = pr.Author.Login
+			}
+			for _, label := range pr.Labels.Nodes {
+				info.Labels = append(info.Labels, label.Name)
+			}
+			commitPullRequests[sha] = info
+			break
+		}
+	}
+	return nil
+}
+
+func fetchPullRequestsREST(shas []string) error {
+	for _, sha := range shas {
+		var prs []*github.PullRequest
+		err := withRateLimit(func() (*github.Response, error) {
+			var resp *github.Response
+			var err error
+			prs, resp, err = Globals.Client.PullRequests.ListPullRequestsWithCommit(Globals.Ctx, Globals.Owner, Globals.Repo, sha, nil)
+			return resp, err
+		})
+		if err != nil {
+			return fmt.Errorf("error fetching pull requests of %s: %w", sha, err)
+		}
+		commitPullRequests[sha] = nil
+		for _, pr := range prs {
+			if pr.MergedAt == nil {
+				continue
+			}
+			info := &pullRequestInfo{Number: pr.GetNumber(), Title: pr.GetTitle(), Body: pr.GetBody(), Author: pr.GetUser().GetLogin()}
+			for _, label := range pr.Labels {
+				info.Labels = append(info.Labels, label.GetName())
+			}
+			commitPullRequests[sha] = info
+			break
+		}
+	}
+	return nil
+}
+
+// getReleaseNoteEntries turns the commits between the old tag and the resolved head into
+// entries, folding the commits of each merged pull request into a single entry
+func getReleaseNoteEntries(oldTag string) ([]releaseNoteEntry, error) {
+	commits, err := getCommitsBetween(oldTag, headRef())
+	if err != nil {
+		return nil, err
+	}
+	// bookkeeping commits made by previous runs (e.g. changelog updates)
+	var kept []*github.RepositoryCommit
+	for _, commit := range commits {
+		if !isAutodeployerCommit(commit.GetCommit().GetMessage()) {
+			kept = append(kept, commit)
+		}
+	}
+	shas := make([]string, len(kept))
+	for i, commit := range kept {
+		shas[i] = commit.GetSHA()
+	}
+	pullRequests, err := getCommitPullRequests(shas)
+	if err != nil {
+		return nil, err
+	}
+	var entries []releaseNoteEntry
+	seenPRs := map[int]bool{}
+	for _, commit := range kept {
+		if merged := pullRequests[commit.GetSHA()]; merged != nil {
+			if seenPRs[merged.Number] {
+				continue
+			}
+			seenPRs[merged.Number] = true
+			entries = append(entries, releaseNoteEntry{Title: merged.Title, Body: merged.Body, Author: merged.Author, PR: merged.Number, Labels: merged.Labels})
+			continue
+		}
+		message := strings.SplitN(strings.TrimSpace(commit.GetCommit().GetMessage()), "\n", 2)
+		entry := releaseNoteEntry{Title: message[0], Author: commit.GetAuthor().GetLogin(), SHA: commit.GetSHA()}
+		if len(message) > 1 {
+			entry.Body = message[1]
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// webURL is the address of the GitHub web UI of the client: github.com, a GHE.com subdomain
+// or the GitHub Enterprise Server host
+func webURL() string {
+	base := *Globals.Client.BaseURL
+	base.Host = strings.TrimPrefix(base.Host, "api.")
+	base.Path, base.RawPath = "", ""
+	return strings.TrimSuffix(base.String(), "/")
+}
+
+// getReleaseBody writes the body of the release of the new tag according to the release notes mode
+func getReleaseBody(oldTag string, newTag string) string {
+	switch Globals.ReleaseNotesMode {
+	case PlainReleaseNotes:
+		return plainReleaseBody
+	case GitHubReleaseNotes:
+		options := &github.GenerateNotesOptions{
+			TagName:         newTag,
+			PreviousTagName: github.String(oldTag),
+			TargetCommitish: github.String(headRef()),
+		}
+		notes, _, err := Globals.Client.Repositories.GenerateReleaseNotes(Globals.Ctx, Globals.Owner, Globals.Repo, options)
+		if err != nil {
+			fmt.Printf("Error when generating release notes through GitHub: %s\nWill use the default release body.\n", err)
+			return plainReleaseBody
+		}
+		return notes.Body
+	}
+	entries, err := getReleaseNoteEntries(oldTag)
+	if err != nil {
+		fmt.Printf("Error when generating release notes: %s\nWill use the default release body.\n", err)
+		return plainReleaseBody
+	}
+	compareURL := fmt.Sprintf("%s/%s/%s/compare/%s...%s", webURL(), Globals.Owner, Globals.Repo, oldTag, newTag)
+	return renderReleaseNotes(oldTag, newTag, compareURL, entries)
+}
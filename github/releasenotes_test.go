@@ -0,0 +1,137 @@
+package gh
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v39/github"
+)
+
+func TestRenderReleaseNotes(t *testing.T) {
+	entries := []releaseNoteEntry{
+		{Title: "feat: add search", Author: "alice", PR: 12},
+		{Title: "Handle empty carts", Author: "bob", PR: 13, Labels: []string{"Bug"}},
+		{Title: "chore: bump deps", Author: "alice", SHA: "0123456789abcdef"},
+		{Title: "refactor!: rename config keys", Author: "carol", PR: 14},
+		{Title: "Tidy up", Body: "BREAKING CHANGE: removes the v1 API", PR: 15},
+		{Title: "fix(api): nil pointer", Author: "bob", PR: 16, Labels: []string{"enhancement"}},
+	}
+
+	expected := `## What's changed since 1.2.3
+
+### ⚠️ Breaking changes
+
+- refactor!: rename config keys by @carol in #14
+- Tidy up in #15
+
+### 🚀 Features
+
+- feat: add search by @alice in #12
+- fix(api): nil pointer by @bob in #16
+
+### 🐛 Fixes
+
+- Handle empty carts by @bob in #13
+
+### 🧰 Other changes
+
+- chore: bump deps by @alice in 0123456
+
+**Contributors**: @alice, @bob, @carol
+
+**Full changelog**: https://github.com/o/r/compare/1.2.3...1.2.4-rc1
+
+_1.2.4-rc1 was created using autodeployer_
+`
+	actual := renderReleaseNotes("1.2.3", "1.2.4-rc1", "https://github.com/o/r/compare/1.2.3...1.2.4-rc1", entries)
+	if actual != expected {
+		t.Errorf("Expected:\n%s\nbut got:\n%s", expected, actual)
+	}
+}
+
+func TestRenderReleaseNotesWithoutChanges(t *testing.T) {
+	expected := "## What's changed since 1.2.3\n\nNo changes.\n\n**Full changelog**: https://example.com\n\n_1.2.3-rc1 was created using autodeployer_\n"
+	if actual := renderReleaseNotes("1.2.3", "1.2.3-rc1", "https://example.com", nil); actual != expected {
+		t.Errorf("Expected:\n%s\nbut got:\n%s", expected, actual)
+	}
+}
+
+func TestWebURL(t *testing.T) {
+	previous := Globals
+	defer func() { Globals = previous }()
+	for _, test := range []struct {
+		baseURL  string
+		expected string
+	}{
+		{"https://api.github.com/", "https://github.com"},
+		{"https://api.acme.ghe.com/", "https://acme.ghe.com"},
+		{"https://github.example.com/api/v3/", "https://github.example.com"},
+	} {
+		client := github.NewClient(nil)
+		client.BaseURL, _ = url.Parse(test.baseURL)
+		Globals = AppContext{Client: client}
+		if actual := webURL(); actual != test.expected {
+			t.Errorf("Expected %s for %s but got %s", test.expected, test.baseURL, actual)
+		}
+	}
+}
+
+func TestGetCommitPullRequests(t *testing.T) {
+	shas := []string{strings.Repeat("a", 40), strings.Repeat("b", 40), strings.Repeat("c", 40)}
+	queries := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries++
+		io.WriteString(w, `{"data":{"repository":{
+			"c0":{"associatedPullRequests":{"nodes":[
+				{"number":11,"title":"Open","mergedAt":null},
+				{"number":12,"title":"feat: add search","body":"body","mergedAt":"2024-10-01T00:00:00Z","author":{"login":"alice"},"labels":{"nodes":[{"name":"enhancement"}]}}
+			]}},
+			"c1":{"associatedPullRequests":{"nodes":[]}},
+			"c2":null
+		}}}`)
+	}))
+	defer server.Close()
+
+	previous, previousPullRequests := Globals, commitPullRequests
+	defer func() { Globals, commitPullRequests = previous, previousPullRequests }()
+	client := github.NewClient(nil)
+	client.BaseURL, _ = url.Parse(server.URL + "/api/v3/")
+	Globals = AppContext{Owner: "o", Repo: "r", Ctx: context.Background(), Client: client}
+	commitPullRequests = map[string]*pullRequestInfo{}
+
+	for i := 0; i < 2; i++ {
+		pullRequests, err := getCommitPullRequests(shas)
+		if err != nil {
+			t.Fatalf("Error returned from getCommitPullRequests: %v", err)
+		}
+		merged := pullRequests[shas[0]]
+		if merged == nil || merged.Number != 12 || merged.Author != "alice" || len(merged.Labels) != 1 {
+			t.Errorf("Expected the merged pull request but got %+v", merged)
+		}
+		if pullRequests[shas[1]] != nil || pullRequests[shas[2]] != nil {
+			t.Errorf("Expected no pull request for the other commits but got %+v", pullRequests)
+		}
+	}
+	if queries != 1 {
+		t.Errorf("Expected a single query but got %d", queries)
+	}
+}
+
+func TestReleaseBranch(t *testing.T) {
+	previous := Globals
+	defer func() { Globals = previous }()
+	Globals = AppContext{Branch: "feature/search"}
+
+	body := withReleaseBranch("## What's changed since 1.2.3\n")
+	if actual := releaseBranch(body); actual != "feature/search" {
+		t.Errorf("Expected feature/search but got %q from:\n%s", actual, body)
+	}
+	if actual := releaseBranch(plainReleaseBody); actual != "" {
+		t.Errorf("Expected no branch but got %q", actual)
+	}
+}
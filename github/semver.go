@@ -0,0 +1,247 @@
+package gh
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a parsed semantic version (https://semver.org)
+type Version struct {
+	Major      int
+	Minor      int
+	Patch      int
+	Prerelease []string
+	Build      []string
+}
+
+// ParseVersion parses a tag such as `1.2.3`, `1.2.3-rc4` or `1.2.3-beta.2+build.7`
+func ParseVersion(tag string) (Version, error) {
+	var v Version
+	rest := tag
+	if idx := strings.Index(rest, "+"); idx != -1 {
+		build := rest[idx+1:]
+		rest = rest[:idx]
+		if build == "" {
+			return v, fmt.Errorf("invalid version %q: empty build metadata", tag)
+		}
+		v.Build = strings.Split(build, ".")
+		for _, id := range v.Build {
+			if !isValidIdentifier(id) {
+				return v, fmt.Errorf("invalid version %q: bad build identifier %q", tag, id)
+			}
+		}
+	}
+	if idx := strings.Index(rest, "-"); idx != -1 {
+		pre := rest[idx+1:]
+		rest = rest[:idx]
+		if pre == "" {
+			return v, fmt.Errorf("invalid version %q: empty pre-release", tag)
+		}
+		v.Prerelease = strings.Split(pre, ".")
+		for _, id := range v.Prerelease {
+			if !isValidIdentifier(id) {
+				return v, fmt.Errorf("invalid version %q: bad pre-release identifier %q", tag, id)
+			}
+			if isNumeric(id) && len(id) > 1 && id[0] == '0' {
+				return v, fmt.Errorf("invalid version %q: pre-release identifier %q has a leading zero", tag, id)
+			}
+		}
+	}
+
+	parts := strings.Split(rest, ".")
+	if len(parts) != 3 {
+		return v, fmt.Errorf("invalid version %q: expected MAJOR.MINOR.PATCH", tag)
+	}
+	nums := make([]int, 3)
+	for i, part := range parts {
+		if !isNumeric(part) {
+			return v, fmt.Errorf("invalid version %q: %q is not a number", tag, part)
+		}
+		if len(part) > 1 && part[0] == '0' {
+			return v, fmt.Errorf("invalid version %q: %q has a leading zero", tag, part)
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return v, fmt.Errorf("invalid version %q: %w", tag, err)
+		}
+		nums[i] = n
+	}
+	v.Major, v.Minor, v.Patch = nums[0], nums[1], nums[2]
+	return v, nil
+}
+
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if len(v.Prerelease) > 0 {
+		s += "-" + strings.Join(v.Prerelease, ".")
+	}
+	if len(v.Build) > 0 {
+		s += "+" + strings.Join(v.Build, ".")
+	}
+	return s
+}
+
+// IsPrerelease reports whether the version carries pre-release identifiers (e.g. `-rc2`)
+func (v Version) IsPrerelease() bool {
+	return len(v.Prerelease) > 0
+}
+
+// Core returns the version without pre-release identifiers and build metadata
+func (v Version) Core() Version {
+	return Version{Major: v.Major, Minor: v.Minor, Patch: v.Patch}
+}
+
+// Compare returns -1, 0 or 1 depending on the precedence of v relative to other.
+// Build metadata is ignored. Pre-release identifiers follow semver rules, except that
+// identifiers like `rc9` and `rc10` that share a prefix are compared by their number.
+func (v Version) Compare(other Version) int {
+	if c := compareInt(v.Major, other.Major); c != 0 {
+		return c
+	}
+	if c := compareInt(v.Minor, other.Minor); c != 0 {
+		return c
+	}
+	if c := compareInt(v.Patch, other.Patch); c != 0 {
+		return c
+	}
+	// a version without pre-release identifiers has higher precedence
+	switch {
+	case len(v.Prerelease) == 0 && len(other.Prerelease) == 0:
+		return 0
+	case len(v.Prerelease) == 0:
+		return 1
+	case len(other.Prerelease) == 0:
+		return -1
+	}
+	for i := 0; i < len(v.Prerelease) && i < len(other.Prerelease); i++ {
+		if c := compareIdentifier(v.Prerelease[i], other.Prerelease[i]); c != 0 {
+			return c
+		}
+	}
+	return compareInt(len(v.Prerelease), len(other.Prerelease))
+}
+
+// bump returns the next version for the given change type. Pre-release versions continue
+// their rc line when the requested change is already covered by the pending release,
+// otherwise a fresh `<label>1` line is started on top of the bumped core version.
+func (v Version) bump(versionChangeType VersionChangeType, label string) (Version, error) {
+	core := v.Core()
+	switch versionChangeType {
+	case Minor:
+		if v.IsPrerelease() {
+			return v.nextPrerelease()
+		}
+		core.Patch++
+	case Major:
+		if v.IsPrerelease() && v.Patch == 0 {
+			return v.nextPrerelease()
+		}
+		core.Minor++
+		core.Patch = 0
+	case Breaking:
+		if v.IsPrerelease() && v.Minor == 0 && v.Patch == 0 {
+			return v.nextPrerelease()
+		}
+		core.Major++
+		core.Minor = 0
+		core.Patch = 0
+	default:
+		return Version{}, fmt.Errorf("unknown version change type %q", versionChangeType)
+	}
+	core.Prerelease = []string{label + "1"}
+	return core, nil
+}
+
+// nextPrerelease increments the trailing number of the last pre-release identifier
+// (`rc9` -> `rc10`, `beta.3` -> `beta.4`), or appends `.1` when there is none
+func (v Version) nextPrerelease() (Version, error) {
+	next := v.Core()
+	next.Prerelease = append([]string{}, v.Prerelease...)
+	last := len(next.Prerelease) - 1
+	prefix, num, ok := splitTrailingNumber(next.Prerelease[last])
+	if !ok {
+		next.Prerelease = append(next.Prerelease, "1")
+		return next, nil
+	}
+	n, err := strconv.Atoi(num)
+	if err != nil {
+		return Version{}, fmt.Errorf("error when converting pre-release number to int: %w", err)
+	}
+	next.Prerelease[last] = fmt.Sprintf("%s%d", prefix, n+1)
+	return next, nil
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareIdentifier(a, b string) int {
+	aNumeric, bNumeric := isNumeric(a), isNumeric(b)
+	switch {
+	case aNumeric && bNumeric:
+		return compareNumericStrings(a, b)
+	case aNumeric:
+		return -1
+	case bNumeric:
+		return 1
+	}
+	aPrefix, aNum, aOk := splitTrailingNumber(a)
+	bPrefix, bNum, bOk := splitTrailingNumber(b)
+	if aOk && bOk && aPrefix == bPrefix {
+		return compareNumericStrings(aNum, bNum)
+	}
+	return strings.Compare(a, b)
+}
+
+// compareNumericStrings compares digit-only strings without overflowing
+func compareNumericStrings(a, b string) int {
+	a = strings.TrimLeft(a, "0")
+	b = strings.TrimLeft(b, "0")
+	if c := compareInt(len(a), len(b)); c != 0 {
+		return c
+	}
+	return strings.Compare(a, b)
+}
+
+// splitTrailingNumber splits `rc12` into `rc` and `12`
+func splitTrailingNumber(id string) (string, string, bool) {
+	i := len(id)
+	for i > 0 && id[i-1] >= '0' && id[i-1] <= '9' {
+		i--
+	}
+	if i == len(id) {
+		return id, "", false
+	}
+	return id[:i], id[i:], true
+}
+
+func isNumeric(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isValidIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
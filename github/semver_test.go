@@ -0,0 +1,84 @@
+package gh
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	testCases := []struct {
+		tag      string
+		expected Version
+	}{
+		{"1.2.3", Version{Major: 1, Minor: 2, Patch: 3}},
+		{"0.0.0", Version{}},
+		{"12.345.6789", Version{Major: 12, Minor: 345, Patch: 6789}},
+		{"1.2.3-rc1", Version{Major: 1, Minor: 2, Patch: 3, Prerelease: []string{"rc1"}}},
+		{"1.2.3-beta.10", Version{Major: 1, Minor: 2, Patch: 3, Prerelease: []string{"beta", "10"}}},
+		{"1.2.3+build.7", Version{Major: 1, Minor: 2, Patch: 3, Build: []string{"build", "7"}}},
+		{"1.2.3-rc.1+sha.5114f85", Version{Major: 1, Minor: 2, Patch: 3, Prerelease: []string{"rc", "1"}, Build: []string{"sha", "5114f85"}}},
+		{"1.2.3-x-y-z", Version{Major: 1, Minor: 2, Patch: 3, Prerelease: []string{"x-y-z"}}},
+	}
+
+	for _, tc := range testCases {
+		actual, err := ParseVersion(tc.tag)
+		if err != nil {
+			t.Errorf("Error returned from ParseVersion(%s): %v", tc.tag, err)
+			continue
+		}
+		if !reflect.DeepEqual(actual, tc.expected) {
+			t.Errorf("ParseVersion(%s): expected %+v but got %+v", tc.tag, tc.expected, actual)
+		}
+		if actual.String() != tc.tag {
+			t.Errorf("Expected %s to round-trip but got %s", tc.tag, actual.String())
+		}
+	}
+}
+
+func TestParseVersionErrors(t *testing.T) {
+	for _, tag := range []string{"", "1", "1.2", "1.2.3.4", "a.b.c", "1.02.3", "1.2.3-", "1.2.3+", "1.2.3-rc..1", "1.2.3-01", "1.2.-3"} {
+		if v, err := ParseVersion(tag); err == nil {
+			t.Errorf("Expected an error for ParseVersion(%s) but got %s", tag, v)
+		}
+	}
+}
+
+func TestVersionCompare(t *testing.T) {
+	// each version has a strictly higher precedence than the one before it
+	ordered := []string{
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc1",
+		"1.0.0-rc2",
+		"1.0.0-rc9",
+		"1.0.0-rc10",
+		"1.0.0",
+		"1.0.1-rc1",
+		"1.0.1",
+		"1.0.10",
+		"1.2.0",
+		"1.10.0",
+		"2.0.0",
+	}
+
+	for i := range ordered {
+		for j := range ordered {
+			a, _ := ParseVersion(ordered[i])
+			b, _ := ParseVersion(ordered[j])
+			expected := compareInt(i, j)
+			if actual := a.Compare(b); actual != expected {
+				t.Errorf("Compare(%s, %s): expected %d but got %d", ordered[i], ordered[j], expected, actual)
+			}
+		}
+	}
+
+	a, _ := ParseVersion("1.2.3+build.1")
+	b, _ := ParseVersion("1.2.3+build.2")
+	if a.Compare(b) != 0 {
+		t.Errorf("Expected build metadata to be ignored when comparing %s and %s", a, b)
+	}
+}
@@ -0,0 +1,190 @@
+package gh
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TagFormat describes how the tags of a repository are laid out.
+// The zero value parses and renders plain semver tags with `-rc<n>` release candidates.
+type TagFormat struct {
+	// e.g. `v{major}.{minor}.{patch}`, `{year}.{month}.{patch}` or `{year}.{0month}.{patch}`
+	// (zero-padded month)
+	Template string
+	// e.g. `-rc{n}` or `-beta.{n}`
+	Prerelease string
+
+	pattern *regexp.Regexp
+	fields  []string
+	calver  bool
+}
+
+const (
+	defaultTagTemplate        = "{major}.{minor}.{patch}"
+	defaultPrereleaseTemplate = "-" + defaultPrereleaseLabel + "{n}"
+)
+
+var tagPlaceholder = regexp.MustCompile(`\{(\w+)\}`)
+
+// NewTagFormat validates the templates of a tag format.
+// Empty templates fall back to the semver defaults.
+func NewTagFormat(template string, prerelease string) (TagFormat, error) {
+	if template == "" && prerelease == "" {
+		return TagFormat{}, nil
+	}
+	if template == "" {
+		template = defaultTagTemplate
+	}
+	if prerelease == "" {
+		prerelease = defaultPrereleaseTemplate
+	}
+	format := TagFormat{Template: template, Prerelease: prerelease}
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	seen := map[string]bool{}
+	last := 0
+	for _, loc := range tagPlaceholder.FindAllStringSubmatchIndex(template, -1) {
+		name := template[loc[2]:loc[3]]
+		expr.WriteString(regexp.QuoteMeta(template[last:loc[0]]))
+		switch name {
+		case "major", "minor", "patch":
+			expr.WriteString(`(0|[1-9]\d*)`)
+		case "year":
+			expr.WriteString(`(\d{4})`)
+			format.calver = true
+		case "month":
+			// not zero-padded, the way Render lays it out
+			expr.WriteString(`([1-9]|1[0-2])`)
+			format.calver = true
+		case "0month":
+			expr.WriteString(`(0[1-9]|1[0-2])`)
+			format.calver = true
+		default:
+			return TagFormat{}, fmt.Errorf("invalid tag format %q: unknown placeholder {%s}", template, name)
+		}
+		// {month} and {0month} are the same field
+		field := strings.TrimPrefix(name, "0")
+		if seen[field] {
+			return TagFormat{}, fmt.Errorf("invalid tag format %q: placeholder {%s} is used twice", template, field)
+		}
+		seen[field] = true
+		format.fields = append(format.fields, name)
+		last = loc[1]
+	}
+	expr.WriteString(regexp.QuoteMeta(template[last:]))
+	if format.calver {
+		if !seen["year"] || !seen["month"] || !seen["patch"] || seen["major"] || seen["minor"] {
+			return TagFormat{}, fmt.Errorf("invalid tag format %q: calendar versions need exactly {year}, {month} and {patch}", template)
+		}
+	} else if !seen["major"] || !seen["minor"] || !seen["patch"] {
+		return TagFormat{}, fmt.Errorf("invalid tag format %q: {major}, {minor} and {patch} are required", template)
+	}
+
+	parts := strings.Split(prerelease, "{n}")
+	if len(parts) != 2 {
+		return TagFormat{}, fmt.Errorf("invalid pre-release format %q: exactly one {n} is required", prerelease)
+	}
+	expr.WriteString("(?:" + regexp.QuoteMeta(parts[0]) + `([1-9]\d*)` + regexp.QuoteMeta(parts[1]) + ")?$")
+
+	pattern, err := regexp.Compile(expr.String())
+	if err != nil {
+		return TagFormat{}, fmt.Errorf("invalid tag format %q: %w", template, err)
+	}
+	format.pattern = pattern
+	return format, nil
+}
+
+// Parse reads a tag laid out according to the format
+func (f TagFormat) Parse(tag string) (Version, error) {
+	if f.pattern == nil {
+		return ParseVersion(tag)
+	}
+	match := f.pattern.FindStringSubmatch(tag)
+	if match == nil {
+		return Version{}, fmt.Errorf("tag %q does not match the format %q", tag, f.Template+f.Prerelease)
+	}
+	var v Version
+	for i, name := range f.fields {
+		n, err := strconv.Atoi(match[i+1])
+		if err != nil {
+			return Version{}, fmt.Errorf("invalid tag %q: %w", tag, err)
+		}
+		switch name {
+		case "major", "year":
+			v.Major = n
+		case "minor", "month", "0month":
+			v.Minor = n
+		case "patch":
+			v.Patch = n
+		}
+	}
+	if rc := match[len(match)-1]; rc != "" {
+		v.Prerelease = []string{rc}
+	}
+	return v, nil
+}
+
+// Render lays out a version according to the format
+func (f TagFormat) Render(v Version) string {
+	if f.pattern == nil {
+		return v.String()
+	}
+	tag := tagPlaceholder.ReplaceAllStringFunc(f.Template, func(placeholder string) string {
+		switch placeholder {
+		case "{major}", "{year}":
+			return strconv.Itoa(v.Major)
+		case "{minor}", "{month}":
+			return strconv.Itoa(v.Minor)
+		case "{0month}":
+			return fmt.Sprintf("%02d", v.Minor)
+		}
+		return strconv.Itoa(v.Patch)
+	})
+	if v.IsPrerelease() {
+		tag += strings.Replace(f.Prerelease, "{n}", v.Prerelease[len(v.Prerelease)-1], 1)
+	}
+	return tag
+}
+
+// Matches reports whether the tag follows the format
+func (f TagFormat) Matches(tag string) bool {
+	_, err := f.Parse(tag)
+	return err == nil
+}
+
+// FinalTag strips the release candidate part of a tag (`v1.2.4-rc3` -> `v1.2.4`)
+func (f TagFormat) FinalTag(tag string) (string, error) {
+	v, err := f.Parse(tag)
+	if err != nil {
+		return "", err
+	}
+	return f.Render(v.Core()), nil
+}
+
+// next computes the version following v. Calendar versions move to the current month,
+// restarting the patch number whenever the month changes, whatever the version change.
+func (f TagFormat) next(v Version, versionChangeType VersionChangeType, now time.Time) (Version, error) {
+	if f.calver && versionChangeType != "" && versionChangeType != Minor {
+		fmt.Printf("Warning: calendar versions don't have %s version changes, %s will follow the current month instead.\n", versionChangeType, f.Render(v))
+	}
+	if !f.calver {
+		label := defaultPrereleaseLabel
+		if f.pattern != nil {
+			label = ""
+		}
+		return v.bump(versionChangeType, label)
+	}
+	if v.IsPrerelease() {
+		return v.nextPrerelease()
+	}
+	next := Version{Major: now.Year(), Minor: int(now.Month())}
+	if next.Major == v.Major && next.Minor == v.Minor {
+		next.Patch = v.Patch + 1
+	}
+	next.Prerelease = []string{"1"}
+	return next, nil
+}
@@ -0,0 +1,137 @@
+package gh
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTagFormatNextTag(t *testing.T) {
+	now := time.Date(2024, time.October, 18, 0, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		template          string
+		prerelease        string
+		oldTag            string
+		versionChangeType VersionChangeType
+		expected          string
+	}{
+		{"", "", "1.2.3", Minor, "1.2.4-rc1"},
+		{"", "", "1.2.3-rc9", Minor, "1.2.3-rc10"},
+		{"v{major}.{minor}.{patch}", "", "v1.2.3", Minor, "v1.2.4-rc1"},
+		{"v{major}.{minor}.{patch}", "", "v1.2.4-rc1", Minor, "v1.2.4-rc2"},
+		{"v{major}.{minor}.{patch}", "", "v1.2.4-rc1", Major, "v1.3.0-rc1"},
+		{"v{major}.{minor}.{patch}", "", "v1.2.3", Breaking, "v2.0.0-rc1"},
+		{"", "-beta.{n}", "1.2.3", Minor, "1.2.4-beta.1"},
+		{"", "-beta.{n}", "1.2.4-beta.9", Minor, "1.2.4-beta.10"},
+		{"release-{major}.{minor}.{patch}", "_pre{n}", "release-1.2.3_pre2", Minor, "release-1.2.3_pre3"},
+		{"{year}.{month}.{patch}", "", "2024.10.1", Minor, "2024.10.2-rc1"},
+		{"{year}.{month}.{patch}", "", "2024.9.4", Minor, "2024.10.0-rc1"},
+		{"{year}.{month}.{patch}", "", "2023.10.4", Breaking, "2024.10.0-rc1"},
+		{"{year}.{month}.{patch}", "", "2024.10.2-rc1", Minor, "2024.10.2-rc2"},
+		{"{year}.{0month}.{patch}", "", "2024.09.4", Minor, "2024.10.0-rc1"},
+		{"{year}.{0month}.{patch}", "", "2024.10.1", Minor, "2024.10.2-rc1"},
+	}
+
+	for _, tc := range testCases {
+		format, err := NewTagFormat(tc.template, tc.prerelease)
+		if err != nil {
+			t.Errorf("Error returned from NewTagFormat(%s, %s): %v", tc.template, tc.prerelease, err)
+			continue
+		}
+		oldVersion, err := format.Parse(tc.oldTag)
+		if err != nil {
+			t.Errorf("Error returned from Parse(%s): %v", tc.oldTag, err)
+			continue
+		}
+		newVersion, err := format.next(oldVersion, tc.versionChangeType, now)
+		if err != nil {
+			t.Errorf("Error returned from next(%s): %v", tc.oldTag, err)
+			continue
+		}
+		if actual := format.Render(newVersion); actual != tc.expected {
+			t.Errorf("%s%s: expected %s after %s but got %s", tc.template, tc.prerelease, tc.expected, tc.oldTag, actual)
+		}
+	}
+}
+
+func TestTagFormatMatches(t *testing.T) {
+	format, err := NewTagFormat("v{major}.{minor}.{patch}", "-beta.{n}")
+	if err != nil {
+		t.Fatalf("Error returned from NewTagFormat: %v", err)
+	}
+	for _, tag := range []string{"v1.2.3", "v0.0.1", "v1.2.3-beta.1", "v10.20.30-beta.12"} {
+		if !format.Matches(tag) {
+			t.Errorf("Expected %s to match the format", tag)
+		}
+	}
+	for _, tag := range []string{"1.2.3", "v1.2", "v1.2.3-rc1", "v1.2.3-beta.0", "v01.2.3", "v1.2.3-beta.1x", "xv1.2.3"} {
+		if format.Matches(tag) {
+			t.Errorf("Expected %s not to match the format", tag)
+		}
+	}
+
+	finalTag, err := format.FinalTag("v1.2.4-beta.3")
+	if err != nil || finalTag != "v1.2.4" {
+		t.Errorf("Expected FinalTag to return v1.2.4 but got %s (%v)", finalTag, err)
+	}
+
+	calver, err := NewTagFormat("{year}.{month}.{patch}", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tag := range []string{"2024.3.1", "2024.12.0-rc2"} {
+		if calver.Render(mustParse(t, calver, tag)) != tag {
+			t.Errorf("Expected %s to render back as itself", tag)
+		}
+	}
+	for _, tag := range []string{"2024.03.1", "2024.0.1", "2024.13.1"} {
+		if calver.Matches(tag) {
+			t.Errorf("Expected %s not to match the format", tag)
+		}
+	}
+
+	padded, err := NewTagFormat("{year}.{0month}.{patch}", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tag := range []string{"2024.03.1", "2024.12.0-rc2"} {
+		if padded.Render(mustParse(t, padded, tag)) != tag {
+			t.Errorf("Expected %s to render back as itself", tag)
+		}
+	}
+	for _, tag := range []string{"2024.3.1", "2024.00.1", "2024.13.1"} {
+		if padded.Matches(tag) {
+			t.Errorf("Expected %s not to match the format", tag)
+		}
+	}
+}
+
+func mustParse(t *testing.T, format TagFormat, tag string) Version {
+	t.Helper()
+	v, err := format.Parse(tag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestNewTagFormatErrors(t *testing.T) {
+	testCases := []struct {
+		template   string
+		prerelease string
+	}{
+		{"v{major}.{minor}", ""},
+		{"{major}.{minor}.{patch}.{build}", ""},
+		{"{major}.{major}.{patch}", ""},
+		{"{year}.{minor}.{patch}", ""},
+		{"{year}.{month}", ""},
+		{"{year}.{month}.{0month}.{patch}", ""},
+		{"", "-rc"},
+		{"", "-rc{n}.{n}"},
+	}
+
+	for _, tc := range testCases {
+		if _, err := NewTagFormat(tc.template, tc.prerelease); err == nil {
+			t.Errorf("Expected an error for NewTagFormat(%s, %s)", tc.template, tc.prerelease)
+		}
+	}
+}
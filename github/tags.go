@@ -3,9 +3,6 @@ package gh
 import (
 	"fmt"
 	"os"
-	"sort"
-	"strconv"
-	"strings"
 	"time"
 
 	"github.com/google/go-github/v39/github"
@@ -17,200 +14,265 @@ func printTags(tags []*github.RepositoryTag) {
 	}
 }
 
-func getLatestTagFromBranch(branch string, tags []*github.RepositoryTag) (*github.RepositoryTag, error) {
-	// Get the branch
-	gitBranch, _, err := Globals.Client.Repositories.GetBranch(Globals.Ctx, Globals.Owner, Globals.Repo, branch, false)
+// ResolveHeadSHA pins the run to the current head of the branch, so that a push landing
+// mid-run can't end up in the release
+func ResolveHeadSHA() error {
+	gitBranch, _, err := Globals.Client.Repositories.GetBranch(Globals.Ctx, Globals.Owner, Globals.Repo, Globals.Branch, true)
 	if err != nil {
-			return nil, err
+		return err
 	}
+	Globals.HeadSHA = gitBranch.GetCommit().GetSHA()
+	return nil
+}
 
-	// Get the commit SHA from the branch
-	commitSHA := *gitBranch.Commit.SHA
-
-	// Find the tag that matches the commit SHA
-	for _, tag := range tags {
-			if *tag.Commit.SHA == commitSHA {
-					return tag, nil
-			}
+// headRef is what the run releases: the resolved head of the branch, or the branch itself
+// when it wasn't resolved
+func headRef() string {
+	if Globals.HeadSHA != "" {
+		return Globals.HeadSHA
 	}
-	// No tag found for the branch (which means it's their first deployment for the branch)
-	return nil, nil
+	return Globals.Branch
 }
 
-func findAndReplaceTag(contentStr, oldTag, newTag string) string {
-    if idx := strings.Index(contentStr, oldTag); idx != -1 {
-        // Find the end of the tag
-        endIdx := idx + len(oldTag)
-        for endIdx < len(contentStr) && (contentStr[endIdx] == '.' || (contentStr[endIdx] >= '0' && contentStr[endIdx] <= '9')) {
-            endIdx++
-        }
-
-        // Get the whole tag
-        wholeOldTag := contentStr[idx:endIdx]
-
-        // Replace the whole tag
-        return strings.Replace(contentStr, wholeOldTag, newTag, -1)
-    }
+func getNewTag(oldTag string, versionChangeType VersionChangeType) (string, error) {
+	oldVersion, err := Globals.TagFormat.Parse(oldTag)
+	if err != nil {
+		return "", fmt.Errorf("error when parsing old tag: %w", err)
+	}
+	newVersion, err := Globals.TagFormat.next(oldVersion, versionChangeType, time.Now())
+	if err != nil {
+		return "", err
+	}
+	return Globals.TagFormat.Render(newVersion), nil
+}
 
-    oldTagParts := strings.Split(oldTag, ".")
-    if len(oldTagParts) > 1 {
-        newOldTag := strings.Join(oldTagParts[:len(oldTagParts)-1], ".")
-        return findAndReplaceTag(contentStr, newOldTag, newTag)
-    }
 
-    fmt.Println("Failed to find a match for the tag in the content")
-    os.Exit(1)
-    return ""
+// listAllTags reads every page of the tags of a repository
+func listAllTags(repo string) ([]*github.RepositoryTag, error) {
+	if repo == Globals.Repo && discoverTags() {
+		return discoveredTags, nil
+	}
+	return listAllTagsREST(repo)
 }
 
-func getNewTag(oldTag string, versionChangeType VersionChangeType) (string, error) {
-	if versionChangeType == Minor { // Minor and rc changes are the same (just increment the last number by 1)
-		// get the last number of the tag and increment it by 1
-		lastVersion := string(oldTag[len(oldTag)-1])
-		minorVersion, err := strconv.Atoi(lastVersion)
+// listAllTagsREST reads the current tags of a repository, never from the tags discovered earlier
+func listAllTagsREST(repo string) ([]*github.RepositoryTag, error) {
+	var tags []*github.RepositoryTag
+	options := &github.ListOptions{PerPage: 100}
+	for {
+		page, resp, err := Globals.Client.Repositories.ListTags(Globals.Ctx, Globals.Owner, repo, options)
 		if err != nil {
-				return "", fmt.Errorf("error when converting last version to int: %s", err)
+			return nil, err
 		}
-		minorVersion++
-		newTag := fmt.Sprintf("%s%d", oldTag[:len(oldTag)-1], minorVersion)
-		isFirstRC := !strings.Contains(oldTag, "-rc")
-		if isFirstRC {
-			newTag += "-rc1"
+		tags = append(tags, page...)
+		if resp.NextPage == 0 {
+			return tags, nil
 		}
-		return newTag, nil
+		options.Page = resp.NextPage
 	}
+}
 
-	return "", fmt.Errorf("major and breaking changes have not been implemented") // Add a return statement with an error message to fix the "missing return" problem
+// forEachRelease walks the releases of a repository page by page (newest first) until fn returns false
+func forEachRelease(repo string, fn func(*github.RepositoryRelease) bool) error {
+	if repo == Globals.Repo && discoverTags() {
+		for _, release := range discoveredReleases {
+			if !fn(release) {
+				return nil
+			}
+		}
+		return nil
+	}
+	return forEachReleaseREST(repo, fn)
 }
 
+// forEachReleaseREST walks the current releases of a repository, never the ones discovered earlier
+func forEachReleaseREST(repo string, fn func(*github.RepositoryRelease) bool) error {
+	options := &github.ListOptions{PerPage: 100}
+	for {
+		releases, resp, err := Globals.Client.Repositories.ListReleases(Globals.Ctx, Globals.Owner, repo, options)
+		if err != nil {
+			return err
+		}
+		for _, release := range releases {
+			if !fn(release) {
+				return nil
+			}
+		}
+		if resp.NextPage == 0 {
+			return nil
+		}
+		options.Page = resp.NextPage
+	}
+}
 
-func getMostRecentTags(options *github.ListOptions) []*github.RepositoryTag {
-	tags, _, err := Globals.Client.Repositories.ListTags(Globals.Ctx, Globals.Owner, Globals.Repo, options)
+func getMostRecentTags() ([]*github.RepositoryTag, error) {
+	tags, err := listAllTags(Globals.Repo)
 	if err != nil {
-		fmt.Printf("Error when fetching tags: %s\n", err)
-		os.Exit(1)
+		return nil, fmt.Errorf("error fetching tags: %w", err)
 	}
-	return tags
+	return tags, nil
 }
 
-func filterTagsByUser(tags []*github.RepositoryTag, user string) ([]*github.RepositoryTag, error) {
+// filterTagsByFormat drops the tags that don't follow the tag format of the repo
+func filterTagsByFormat(tags []*github.RepositoryTag, format TagFormat) []*github.RepositoryTag {
 	var filteredTags []*github.RepositoryTag
 	for _, tag := range tags {
-		commit, _, err := Globals.Client.Repositories.GetCommit(Globals.Ctx, Globals.Owner, Globals.Repo, *tag.Commit.SHA, nil)
-		if err != nil {
-			return nil, err
-		}
-
-		if commit.Author != nil && commit.Author.GetLogin() == user {
+		if format.Matches(tag.GetName()) {
 			filteredTags = append(filteredTags, tag)
 		}
 	}
-	return filteredTags, nil
+	return filteredTags
+}
+
+func filterTagsByUser(tags []*github.RepositoryTag, authors map[string]bool) ([]*github.RepositoryTag, error) {
+	commits, err := resolveTagCommits(tags)
+	if err != nil {
+		return nil, err
+	}
+	return filterTagsByAuthors(tags, commits, authors), nil
 }
 
-// doesn't work yet
-func filterTagsbyDayCutoff(tags []*github.RepositoryTag, daysCutoff int) ([]*github.RepositoryTag, error) {
+// getCommitDates resolves the committer date of every tagged commit
+func getCommitDates(tags []*github.RepositoryTag) (map[string]time.Time, error) {
+	commits, err := resolveTagCommits(tags)
+	if err != nil {
+		return nil, err
+	}
+	dates := map[string]time.Time{}
+	for sha, commit := range commits {
+		dates[sha] = commit.Date
+	}
+	return dates, nil
+}
+
+// filterTagsByDayCutoff drops the tags whose commit is older than the cutoff, keeping the original order
+func filterTagsByDayCutoff(tags []*github.RepositoryTag, commitDates map[string]time.Time, daysCutoff int, now time.Time) []*github.RepositoryTag {
 	var tagList []*github.RepositoryTag
-	cutoff := time.Now().AddDate(0, 0, -daysCutoff)
+	cutoff := now.AddDate(0, 0, -daysCutoff)
 	for _, tag := range tags {
-		// the issue ⬇️
-		date := tag.GetCommit().GetCommitter().GetDate().Format(time.RFC3339)
-		fmt.Printf("Date: %s\n", date)
-		parsedDate, err := time.Parse(time.RFC3339, date)
-		if err != nil {
-			return nil, fmt.Errorf("error parsing date: %w", err)
-		}
-		if parsedDate.Before(cutoff) {
+		date, ok := commitDates[tag.GetCommit().GetSHA()]
+		if !ok || date.Before(cutoff) {
 			continue
 		}
 		tagList = append(tagList, tag)
 	}
-
-	sort.Slice(tagList, func(i, j int) bool {
-		return tagList[i].GetCommit().GetCommitter().GetDate().Before(tagList[j].GetCommit().GetCommitter().GetDate())
-	})
-
-	return tagList, nil
+	return tagList
 }
 
-func getLatestOfficialReleaseTag(repo string) (*github.RepositoryRelease, error) {
-	releases, _, err := Globals.Client.Repositories.ListReleases(Globals.Ctx, Globals.Owner, repo, nil)
+// getOfficialReleaseTags lists the published non-prerelease releases that follow the tag format
+func getOfficialReleaseTags(repo string) ([]string, error) {
+	var releaseTags []string
+	err := forEachRelease(repo, func(release *github.RepositoryRelease) bool {
+		if release.GetPrerelease() || release.GetDraft() {
+				return true
+		}
+		if !Globals.TagFormat.Matches(release.GetTagName()) {
+				return true
+		}
+		releaseTags = append(releaseTags, release.GetTagName())
+		return true
+	})
 	if err != nil {
 			return nil, fmt.Errorf("error fetching releases: %w", err)
 	}
-	for _, release := range releases {
-		if release.GetPrerelease() {
-				continue
-		}
-		return release, nil
-	}
-
-	return nil, fmt.Errorf("no non-pre-release found for repository %s/%s", Globals.Owner, repo)
+	return releaseTags, nil
 }
 
-func getOldTag() string {
+func getOldTag() (string, error) {
 	// 1. get the tags and apply filters to them for more accurate results
 	fmt.Println("\n[1/5] Determining new release tag...")
-	options := &github.ListOptions{}
-	tags := getMostRecentTags(options)
-	// filter tags by the last 30 days
-	// finteredTagsByDayCutoff, err := filterTagsbyDayCutoff(tags, 30)
-	// if err != nil {
-	// 	fmt.Printf("Error when filtering tags by day cutoff: %s\nWill not filter out tags older than 30 days.\n", err)
-	// } else {
-	// 	fmt.Printf("Filtering tags by day cutoff: %t\n", len(tags) == len(finteredTagsByDayCutoff))
-	// 	tags = finteredTagsByDayCutoff
-	// }
+	tags, err := getMostRecentTags()
+	if err != nil {
+		return "", err
+	}
+	// ignore tags that follow a different scheme (e.g. tags of another tool)
+	tags = filterTagsByFormat(tags, Globals.TagFormat)
+	// filter out tags older than the configured window (e.g. abandoned rc tags)
+	if Globals.TagDayCutoff > 0 {
+		commitDates, err := getCommitDates(tags)
+		if err != nil {
+			fmt.Printf("Error when fetching tag dates: %s\nWill not filter out tags older than %d days.\n", err, Globals.TagDayCutoff)
+		} else {
+			filteredTagsByDayCutoff := filterTagsByDayCutoff(tags, commitDates, Globals.TagDayCutoff, time.Now())
+			fmt.Printf("Ignoring %d tag(s) older than %d days.\n", len(tags)-len(filteredTagsByDayCutoff), Globals.TagDayCutoff)
+			tags = filteredTagsByDayCutoff
+		}
+	}
 
 	// filter even more based on the users who created the tag
-	username, err := getUsername()
+	policy := string(Globals.OwnershipPolicy)
+	authors, err := getAllowedAuthors()
 	if err != nil {
-		fmt.Printf("Error when fetching username: %s\nWill not filter out tags not created by you.\n", err)
-	} else {
-		filteredTagsByUser, err := filterTagsByUser(tags, username)
+		fmt.Printf("Error when determining whose tags can be continued: %s\nWill not filter out tags by their author.\n", err)
+	} else if authors != nil {
+		policy = describeOwnershipPolicy(authors)
+		filteredTagsByUser, err := filterTagsByUser(tags, authors)
 		if err != nil {
-			fmt.Printf("Error when filtering tags by user: %s\nWill not filter out tags not created by you.\n", err)
+			fmt.Printf("Error when filtering tags by user: %s\nWill not filter out tags by their author.\n", err)
 		} else {
 			tags = filteredTagsByUser
 		}
 	}
-	// 2. get the latest tag from the branch
-	latestTagFromBranch, err := getLatestTagFromBranch(Globals.Branch, tags)
+	fmt.Printf("RC ownership policy: %s\n", policy)
+	// 2. continue the rc line at the head of the branch, or start one from the latest release
+	if Globals.HeadSHA == "" {
+		if err := ResolveHeadSHA(); err != nil {
+			return "", fmt.Errorf("error fetching the head of %s: %w", Globals.Branch, err)
+		}
+	}
+	officialReleases, err := getOfficialReleaseTags(Globals.Repo)
 	if err != nil {
-		fmt.Printf("Error when fetching latest tag: %s\n", err)
-		os.Exit(1)
+		return "", err
 	}
-
-	isFirstRC := latestTagFromBranch == nil
-	if isFirstRC {
-		fmt.Println("No tags found for the branch. Assuming this is the first deployment attempt.")
-		latestOfficialReleaseTag, err := getLatestOfficialReleaseTag(Globals.Repo)
-		if err != nil {
-			fmt.Printf("Error when fetching latest official release tag: %s\n", err)
-			os.Exit(1)
-		}
-		return latestOfficialReleaseTag.GetTagName()
-	} else {
-		// there is an rc tag previously created, so we will use that as the old tag
-		return tags[0].GetName()
+	oldTag, reason, err := selectOldTag(tags, Globals.Branch, Globals.HeadSHA, officialReleases, Globals.TagFormat)
+	if err != nil {
+		return "", fmt.Errorf("error selecting the old tag: %w", err)
 	}
+	fmt.Printf("Old tag %s: %s.\n", oldTag, reason)
+	return oldTag, nil
 }
 
 // getNewReleaseTag determines the new release tag
 func GetOldAndNewReleaseTag(versionChageType VersionChangeType) (string, string, error) {
-	// 0. set default params if not provided
-	if versionChageType == "" {
-		versionChageType = Minor
-	}
 	oldTag := Globals.UserDefinedOldTag
 	if oldTag == "" {
-		oldTag = getOldTag()
+		var err error
+		if oldTag, err = getOldTag(); err != nil {
+			return "", "", err
+		}
+		if Globals.OldTagSource == DeployedOldTagSource && len(Globals.StagingManifests) > 0 {
+			deployedTag, err := getDeployedTag(Globals.StagingManifests[0])
+			if err != nil {
+				fmt.Printf("Error when reading the deployed tag: %s\nWill use %s, guessed from the tags, as the old tag.\n", err, oldTag)
+			} else {
+				if deployedTag != oldTag {
+					fmt.Printf("Warning: %s is deployed in %s but the tags suggest %s. Using the deployed tag.\n", deployedTag, Globals.DeploymentsRepo, oldTag)
+				}
+				oldTag = deployedTag
+			}
+		}
+	}
+	// set default params if not provided
+	if versionChageType == "" && Globals.InferVersionChange {
+		inferredVersionChangeType, err := determineVersionChangeType(oldTag)
+		if err != nil {
+			fmt.Printf("Error when inferring the version change from commits: %s\nWill default to a %s version change.\n", err, Minor)
+		} else {
+			versionChageType = inferredVersionChangeType
+		}
+	}
+	if versionChageType == "" {
+		versionChageType = Minor
 	}
 
 	newTag, err := getNewTag(oldTag, versionChageType)
 	if err != nil {
 		return oldTag, "", err
 	}
+	newTag, err = ensureTagIsFree(newTag)
+	if err != nil {
+		return oldTag, "", err
+	}
 
 	return oldTag, newTag, nil
 }
@@ -232,23 +294,45 @@ func GetOldAndNewReleaseTag(versionChageType VersionChangeType) (string, string,
 	// }
 	// return oldTag, newTag
 
-// createNewRelease creates a new release
-func CreateNewRelease(newTag string) {
+// createNewRelease creates a new release. With draft releases or annotated tags enabled, the
+// tag is created first. Drafts stay unpublished until FinalizeRelease publishes them.
+func CreateNewRelease(oldTag string, newTag string) *github.RepositoryRelease {
 	fmt.Println("[2/5] Creating new release...")
+	if !Globals.TagFormat.Matches(newTag) {
+		fmt.Printf("Refusing to create release %s: it does not follow the tag format of %s\n", newTag, Globals.Repo)
+		os.Exit(1)
+	}
+	body := getReleaseBody(oldTag, newTag)
 	release := &github.RepositoryRelease{
 		TagName:         github.String(newTag),
-		TargetCommitish: github.String(Globals.Branch),
+		TargetCommitish: github.String(headRef()),
 		Name:            github.String(newTag),
-		Body:            github.String("Release created using autodeployer"),
-		Draft:           github.Bool(false),
-		Prerelease:      github.Bool(Globals.IsPrerelease),
+		Body:            github.String(withReleaseBranch(body)),
+		Draft:           github.Bool(draftReleases()),
+		Prerelease:      github.Bool(heldAsPrerelease(Globals.IsPrerelease)),
+	}
+	if needsTagUpfront() {
+		if err := createTag(newTag, headRef(), tagMessage(oldTag, newTag, body)); err != nil {
+			fmt.Printf("Failed to create tag %s: %s\n", newTag, err)
+			os.Exit(1)
+		}
 	}
-	_, _, err := Globals.Client.Repositories.CreateRelease(Globals.Ctx, Globals.Owner, Globals.Repo, release)
+	createdRelease, _, err := Globals.Client.Repositories.CreateRelease(Globals.Ctx, Globals.Owner, Globals.Repo, release)
 	if err != nil {
 		fmt.Printf("Failed to create release: %s\n", err)
 		os.Exit(1)
 	}
-	fmt.Printf("Release %s was successfully created.\n", newTag)
+	if err := UploadReleaseAssets(createdRelease, oldTag); err != nil {
+		fmt.Printf("Failed to attach the assets of %s: %s\n", newTag, err)
+		FinalizeRelease(createdRelease, false, "its assets could not be attached")
+		os.Exit(1)
+	}
+	if draftReleases() {
+		fmt.Printf("Draft release %s was successfully created. It will be published once it's deployed.\n", newTag)
+	} else {
+		fmt.Printf("Release %s was successfully created.\n", newTag)
+	}
+	return createdRelease
 	// fmt.Println("Waiting 10 seconds before checking the image build workflow...\n")
 	// time.Sleep(10 * time.Second)
-}
\ No newline at end of file
+}
@@ -1,7 +1,12 @@
 package gh
 
 import (
+	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/google/go-github/v39/github"
 )
 
 func TestGetNewTag(t *testing.T) {
@@ -12,6 +17,22 @@ func TestGetNewTag(t *testing.T) {
 	}{
 		{"1.0.1", Minor, "1.0.2-rc1"},
 		{"1.0.1-rc1", Minor, "1.0.1-rc2"},
+		{"1.0.9", Minor, "1.0.10-rc1"},
+		{"1.0.1-rc9", Minor, "1.0.1-rc10"},
+		{"1.0.1-rc10", Minor, "1.0.1-rc11"},
+		{"10.20.30", Minor, "10.20.31-rc1"},
+		{"1.2.3+build.5", Minor, "1.2.4-rc1"},
+		{"1.2.3-rc2+build.5", Minor, "1.2.3-rc3"},
+		{"1.2.3-beta.2", Minor, "1.2.3-beta.3"},
+		{"1.2.3-alpha", Minor, "1.2.3-alpha.1"},
+		{"1.2.3", Major, "1.3.0-rc1"},
+		{"1.2.9", Major, "1.3.0-rc1"},
+		{"1.2.3-rc4", Major, "1.3.0-rc1"},
+		{"1.3.0-rc4", Major, "1.3.0-rc5"},
+		{"1.2.3", Breaking, "2.0.0-rc1"},
+		{"9.2.3", Breaking, "10.0.0-rc1"},
+		{"1.3.0-rc2", Breaking, "2.0.0-rc1"},
+		{"2.0.0-rc2", Breaking, "2.0.0-rc3"},
 	}
 
 	for _, tc := range testCases {
@@ -21,30 +42,113 @@ func TestGetNewTag(t *testing.T) {
 		}
 
 		if actual != tc.expected {
-			t.Errorf("Expected %s but got %s", tc.expected, actual)
+			t.Errorf("getNewTag(%s, %s): expected %s but got %s", tc.oldTag, tc.versionChangeType, tc.expected, actual)
 		}
 	}
 }
 
-func TestFindAndReplaceTag(t *testing.T) {
-    testCases := []struct {
-        contentStr string
-        oldTag     string
-        newTag     string
-        expected   string
-    }{
-        {"This is a test string with tag 1.2.3", "1.2.3", "2.0.0", "This is a test string with tag 2.0.0"},
-        {"This is a test string with tag 1.2.2", "1.2.3", "1.2.4-rc1", "This is a test string with tag 1.2.4-rc1"},
-		{"This is a test string with tag 1.2.3", "1.3.0", "1.3.0-rc1", "This is a test string with tag 1.3.0-rc1"},
-        {"This is a test string with tag 1.2.2", "1.2.3-rc1", "1.2.4-rc2", "This is a test string with tag 1.2.4-rc2"},
-        {"This is a test string with tag 1.2.3-rc1", "1.2.3-rc2", "1.2.4-rc3", "This is a test string with tag 1.2.4-rc3"},
-		// {"This is a test string with tag 1.2.3", "2.0.0", "2.0.1-rc1", "This is a test string with tag 2.0.1-rc1"},
-    }
-
-    for _, tc := range testCases {
-        actual := findAndReplaceTag(tc.contentStr, tc.oldTag, tc.newTag)
-        if actual != tc.expected {
-            t.Errorf("Expected '%s' but got '%s'", tc.expected, actual)
-        }
-    }
-}
\ No newline at end of file
+func TestGetNewTagErrors(t *testing.T) {
+	testCases := []struct {
+		oldTag            string
+		versionChangeType VersionChangeType
+	}{
+		{"", Minor},
+		{"1.2", Minor},
+		{"1.2.x", Minor},
+		{"01.2.3", Minor},
+		{"1.2.3-", Minor},
+		{"1.2.3-rc_1", Minor},
+		{"1.2.3", VersionChangeType("sideways")},
+	}
+
+	for _, tc := range testCases {
+		if actual, err := getNewTag(tc.oldTag, tc.versionChangeType); err == nil {
+			t.Errorf("Expected an error for getNewTag(%s, %s) but got %s", tc.oldTag, tc.versionChangeType, actual)
+		}
+	}
+}
+
+func TestResolveTagCollision(t *testing.T) {
+	taken := map[string]bool{"1.2.3": true, "1.2.4-rc1": true, "1.2.4-rc2": true, "1.2.4-rc4": true, "1.3.0-rc7": true}
+	origin := func(tag string) string { return "released by @alice on 2024-10-18 09:30" }
+	testCases := []struct {
+		newTag   string
+		policy   TagCollisionPolicy
+		expected string
+		wantErr  string
+	}{
+		{"1.2.4-rc3", RenumberOnCollision, "1.2.4-rc3", ""},
+		{"1.2.4-rc3", FailOnCollision, "1.2.4-rc3", ""},
+		{"1.2.4-rc1", RenumberOnCollision, "1.2.4-rc5", ""},
+		{"1.2.4-rc2", RenumberOnCollision, "1.2.4-rc5", ""},
+		{"1.2.4-rc4", RenumberOnCollision, "1.2.4-rc5", ""},
+		{"1.2.4-rc2", FailOnCollision, "", "tag 1.2.4-rc2 already exists (released by @alice on 2024-10-18 09:30)"},
+		{"1.2.3", RenumberOnCollision, "", "final releases can't be renumbered"},
+	}
+
+	for _, tc := range testCases {
+		actual, err := resolveTagCollision(tc.newTag, taken, TagFormat{}, tc.policy, origin)
+		if tc.wantErr == "" && err != nil || tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+			t.Errorf("resolveTagCollision(%s, %s): expected error %q but got %v", tc.newTag, tc.policy, tc.wantErr, err)
+		}
+		if actual != tc.expected {
+			t.Errorf("resolveTagCollision(%s, %s): expected %s but got %s", tc.newTag, tc.policy, tc.expected, actual)
+		}
+	}
+}
+
+func TestFilterTagsByDayCutoff(t *testing.T) {
+	now := time.Date(2024, time.October, 18, 12, 0, 0, 0, time.UTC)
+	tags := []*github.RepositoryTag{
+		{Name: github.String("1.2.4-rc2"), Commit: &github.Commit{SHA: github.String("c")}},
+		{Name: github.String("1.2.4-rc1"), Commit: &github.Commit{SHA: github.String("b")}},
+		{Name: github.String("1.2.3"), Commit: &github.Commit{SHA: github.String("a")}},
+		{Name: github.String("1.2.3-rc1"), Commit: &github.Commit{SHA: github.String("a")}},
+		{Name: github.String("unknown"), Commit: &github.Commit{SHA: github.String("z")}},
+	}
+	commitDates := map[string]time.Time{
+		"a": now.AddDate(0, 0, -45),
+		"b": now.AddDate(0, 0, -30).Add(time.Hour),
+		"c": now.AddDate(0, 0, -1),
+	}
+
+	var actual []string
+	for _, tag := range filterTagsByDayCutoff(tags, commitDates, 30, now) {
+		actual = append(actual, tag.GetName())
+	}
+	expected := []string{"1.2.4-rc2", "1.2.4-rc1"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Expected %v but got %v", expected, actual)
+	}
+}
+
+func TestFilterTagsByAuthors(t *testing.T) {
+	tags := []*github.RepositoryTag{
+		{Name: github.String("1.2.4-rc3"), Commit: &github.Commit{SHA: github.String("c")}},
+		{Name: github.String("1.2.4-rc2"), Commit: &github.Commit{SHA: github.String("b")}},
+		{Name: github.String("1.2.4-rc1"), Commit: &github.Commit{SHA: github.String("a")}},
+	}
+	commits := map[string]commitInfo{
+		"a": {Author: "me"},
+		"b": {Author: "teammate"},
+		"c": {Author: "stranger"},
+	}
+	testCases := []struct {
+		authors  map[string]bool
+		expected []string
+	}{
+		{map[string]bool{"me": true}, []string{"1.2.4-rc1"}},
+		{map[string]bool{"me": true, "teammate": true}, []string{"1.2.4-rc2", "1.2.4-rc1"}},
+		{map[string]bool{"nobody": true}, nil},
+	}
+
+	for _, tc := range testCases {
+		var actual []string
+		for _, tag := range filterTagsByAuthors(tags, commits, tc.authors) {
+			actual = append(actual, tag.GetName())
+		}
+		if !reflect.DeepEqual(actual, tc.expected) {
+			t.Errorf("filterTagsByAuthors(%v): expected %v but got %v", tc.authors, tc.expected, actual)
+		}
+	}
+}
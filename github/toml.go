@@ -0,0 +1,201 @@
+package gh
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// tomlKey bumps the string at a dotted key of a TOML file (e.g. `image.tag`), held in a
+// `[table]` or as a dotted key
+type tomlKey struct {
+	ImageURL string
+	Key      string
+}
+
+// readTOMLKey reads a dotted key of bare, "basic" and 'literal' parts at the start of s and
+// returns its parts and the length it spans
+func readTOMLKey(s string) ([]string, int, error) {
+	var parts []string
+	i := 0
+	for {
+		for i < len(s) && (s[i] == ' ' || s[i] == '\t') {
+			i++
+		}
+		if i == len(s) {
+			return nil, 0, fmt.Errorf("incomplete key %q", s)
+		}
+		switch s[i] {
+		case '"', '\'':
+			end := strings.IndexByte(s[i+1:], s[i])
+			if end == -1 || (s[i] == '"' && strings.Contains(s[i+1:i+1+end], `\`)) {
+				return nil, 0, fmt.Errorf("unsupported key %q", s)
+			}
+			parts = append(parts, s[i+1:i+1+end])
+			i += end + 2
+		default:
+			start := i
+			for i < len(s) && (s[i] == '_' || s[i] == '-' || s[i] >= 'a' && s[i] <= 'z' || s[i] >= 'A' && s[i] <= 'Z' || s[i] >= '0' && s[i] <= '9') {
+				i++
+			}
+			if i == start {
+				return nil, 0, fmt.Errorf("invalid key %q", s)
+			}
+			parts = append(parts, s[start:i])
+		}
+		for i < len(s) && (s[i] == ' ' || s[i] == '\t') {
+			i++
+		}
+		if i == len(s) || s[i] != '.' {
+			return parts, i, nil
+		}
+		i++
+	}
+}
+
+func sameTOMLKey(table []string, key []string, target []string) bool {
+	if len(table)+len(key) != len(target) {
+		return false
+	}
+	for i, part := range append(append([]string{}, table...), key...) {
+		if part != target[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// scanTOMLValue follows the arrays, inline tables and multi-line strings of a value that span
+// several lines, and returns how deep the value is still nested and the delimiter of the
+// multi-line string it's still in
+func scanTOMLValue(s string, depth int, multiline string) (int, string) {
+	for i := 0; i < len(s); i++ {
+		if multiline != "" {
+			if multiline == `"""` && s[i] == '\\' {
+				i++
+			} else if strings.HasPrefix(s[i:], multiline) {
+				i += len(multiline) - 1
+				multiline = ""
+			}
+			continue
+		}
+		switch s[i] {
+		case '#':
+			return depth, ""
+		case '"', '\'':
+			if delimiter := strings.Repeat(s[i:i+1], 3); strings.HasPrefix(s[i:], delimiter) {
+				multiline = delimiter
+				i += 2
+				continue
+			}
+			quote := s[i]
+			for i++; i < len(s) && s[i] != quote; i++ {
+				if quote == '"' && s[i] == '\\' {
+					i++
+				}
+			}
+		case '[', '{':
+			depth++
+		case ']', '}':
+			depth--
+		}
+	}
+	return depth, multiline
+}
+
+// findTOMLString returns the byte range of the single-line string (quotes excluded) assigned to
+// the key, or -1 when it isn't written as such (inline tables, multi-line strings, ...)
+func findTOMLString(content string, key []string) (int, int, error) {
+	var table []string
+	inArray := false
+	// the nesting of the value being read, whose lines are neither headers nor keys
+	depth, multiline := 0, ""
+	offset := 0
+	for _, line := range strings.SplitAfter(content, "\n") {
+		lineStart := offset
+		offset += len(line)
+		if depth > 0 || multiline != "" {
+			depth, multiline = scanTOMLValue(line, depth, multiline)
+			continue
+		}
+		trimmed := strings.TrimLeft(line, " \t")
+		indent := len(line) - len(trimmed)
+		switch {
+		case trimmed == "" || trimmed[0] == '#' || trimmed[0] == '\n' || trimmed[0] == '\r':
+			continue
+		case strings.HasPrefix(trimmed, "[["):
+			// keys of arrays of tables can't be addressed
+			inArray = true
+			continue
+		case trimmed[0] == '[':
+			parts, _, err := readTOMLKey(trimmed[1:])
+			if err != nil {
+				return 0, 0, err
+			}
+			table, inArray = parts, false
+			continue
+		}
+		if inArray {
+			continue
+		}
+		parts, length, err := readTOMLKey(trimmed)
+		if err != nil {
+			continue
+		}
+		rest := strings.TrimLeft(trimmed[length:], " \t")
+		if !strings.HasPrefix(rest, "=") {
+			continue
+		}
+		value := strings.TrimLeft(rest[1:], " \t")
+		if !sameTOMLKey(table, parts, key) {
+			depth, multiline = scanTOMLValue(value, 0, "")
+			continue
+		}
+		if value == "" || (value[0] != '"' && value[0] != '\'') || strings.HasPrefix(value, `"""`) || strings.HasPrefix(value, "'''") {
+			return -1, -1, nil
+		}
+		end := strings.IndexByte(value[1:], value[0])
+		if end == -1 {
+			return -1, -1, nil
+		}
+		start := lineStart + indent + (len(trimmed) - len(value)) + 1
+		return start, start + end, nil
+	}
+	return -1, -1, nil
+}
+
+func (t tomlKey) Bump(content string, newTag string) (string, []string, error) {
+	key, length, err := readTOMLKey(t.Key)
+	if err != nil || length != len(t.Key) {
+		return "", nil, fmt.Errorf("invalid TOML key %q", t.Key)
+	}
+	var data map[string]interface{}
+	metadata, err := toml.Decode(content, &data)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid TOML: %w", err)
+	}
+	if !metadata.IsDefined(key...) {
+		return "", nil, fmt.Errorf("no key %s found", t.Key)
+	}
+	if metadata.Type(key...) != "String" {
+		return "", nil, fmt.Errorf("the value of %s is not a string", t.Key)
+	}
+	var value interface{} = data
+	for _, part := range key {
+		value = value.(map[string]interface{})[part]
+	}
+	start, end, err := findTOMLString(content, key)
+	if err != nil {
+		return "", nil, err
+	}
+	// the written string has to be the decoded one, so that escapes are never rewritten
+	if start == -1 || content[start:end] != value.(string) {
+		return "", nil, fmt.Errorf("can't rewrite the value of %s, only single-line strings without escapes can be", t.Key)
+	}
+	bumped, previousTag, err := bumpTagValue(content[start:end], t.ImageURL, newTag)
+	if err != nil {
+		return "", nil, fmt.Errorf("the value of %s: %w", t.Key, err)
+	}
+	return content[:start] + bumped + content[end:], []string{previousTag}, nil
+}
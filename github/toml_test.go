@@ -0,0 +1,44 @@
+package gh
+
+import "testing"
+
+func TestTOMLKeyGolden(t *testing.T) {
+	testCases := []struct {
+		input        string
+		golden       string
+		manifest     ManifestFile
+		previousTags []string
+	}{
+		{"settings.toml", "settings.toml.golden", ManifestFile{Type: TOMLManifest, ImageURL: "ghcr.io/org/app", KeyPath: "app_image"}, []string{"1.2.3"}},
+		{"config.toml", "config-table.toml.golden", ManifestFile{Type: TOMLManifest, ImageURL: "ghcr.io/org/app", KeyPath: "image.tag"}, []string{"1.2.3"}},
+		{"config.toml", "config-quoted-key.toml.golden", ManifestFile{Type: TOMLManifest, KeyPath: `deploy."app.version"`}, []string{"1.2.3"}},
+	}
+
+	for _, tc := range testCases {
+		checkGolden(t, tc.input, tc.golden, tc.manifest, tc.previousTags)
+	}
+}
+
+func TestTOMLKey(t *testing.T) {
+	testCases := []struct {
+		name    string
+		content string
+		key     string
+	}{
+		{"another image", "image = \"ghcr.io/org/worker:1.2.3\"\n", "image"},
+		{"not a string", "replicas = 2\n", "replicas"},
+		{"missing", "image = \"ghcr.io/org/app:1.2.3\"\n", "app.image"},
+		{"inline table", "image = { tag = \"1.2.3\" }\n", "image.tag"},
+		{"escaped", "tag = \"1.2\\u002E3\"\n", "tag"},
+		{"array of tables", "[[image]]\ntag = \"1.2.3\"\n", "image.tag"},
+		{"invalid TOML", "tag = \n", "tag"},
+		{"inside a multi-line string", "notes = \"\"\"\ntag = \"1.2.3\"\n\"\"\"\n", "tag"},
+	}
+
+	for _, tc := range testCases {
+		_, _, err := tomlKey{ImageURL: "ghcr.io/org/app", Key: tc.key}.Bump(tc.content, "1.2.4-rc1")
+		if err == nil {
+			t.Errorf("%s: expected an error", tc.name)
+		}
+	}
+}
@@ -0,0 +1,291 @@
+package gh
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlImageRule says which image references of a YAML manifest are bumped. Without a key path
+// or container name, every reference to the image is.
+type yamlImageRule struct {
+	ImageURL string
+	// e.g. `spec.template.spec.containers[name=app].image`, see resolveYAMLPath
+	KeyPath string
+	// the `name` of the container (or any mapping with an `image` key) whose image is bumped
+	Container string
+}
+
+func (r yamlImageRule) String() string {
+	switch {
+	case r.KeyPath != "":
+		return fmt.Sprintf("%s at %s", r.ImageURL, r.KeyPath)
+	case r.Container != "":
+		return fmt.Sprintf("%s of container %s", r.ImageURL, r.Container)
+	}
+	return r.ImageURL
+}
+
+func (r yamlImageRule) Bump(content string, newTag string) (string, []string, error) {
+	return bumpYAMLImage(content, r, newTag)
+}
+
+// imageReference matches `<image>`, `<image>:<tag>` and `<image>[:<tag>]@<digest>`
+func imageReference(imageURL string) *regexp.Regexp {
+	return regexp.MustCompile(`^` + regexp.QuoteMeta(imageURL) + `(?::([\w][\w.-]{0,127}))?(?:@[\w+.-]+:[0-9a-fA-F]+)?$`)
+}
+
+// parseYAMLDocuments decodes every document of a (possibly multi-document) YAML file
+func parseYAMLDocuments(content string) ([]*yaml.Node, error) {
+	decoder := yaml.NewDecoder(strings.NewReader(content))
+	var documents []*yaml.Node
+	for {
+		var document yaml.Node
+		err := decoder.Decode(&document)
+		if errors.Is(err, io.EOF) {
+			return documents, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, &document)
+	}
+}
+
+// walkYAML calls fn for every node under the given one, without following aliases
+func walkYAML(node *yaml.Node, fn func(*yaml.Node)) {
+	fn(node)
+	for _, child := range node.Content {
+		walkYAML(child, fn)
+	}
+}
+
+// mappingValue returns the value of a key of a mapping node
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+var yamlPathSegment = regexp.MustCompile(`^([^\[\]]*)((?:\[[^\]]+\])*)$`)
+var yamlPathSelector = regexp.MustCompile(`\[([^\]]+)\]`)
+
+// resolveYAMLPath finds the nodes at a dotted key path. A segment can be followed by selectors
+// picking sequence items: `[2]` by index, `[*]` all of them, `[name=app]` by the value of a key.
+func resolveYAMLPath(root *yaml.Node, path string) ([]*yaml.Node, error) {
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		root = root.Content[0]
+	}
+	nodes := []*yaml.Node{root}
+	for _, segment := range strings.Split(path, ".") {
+		match := yamlPathSegment.FindStringSubmatch(segment)
+		if match == nil || (match[1] == "" && match[2] == "") {
+			return nil, fmt.Errorf("invalid key path %q", path)
+		}
+		var next []*yaml.Node
+		for _, node := range nodes {
+			if match[1] == "" {
+				next = append(next, node)
+			} else if value := mappingValue(node, match[1]); value != nil {
+				next = append(next, value)
+			}
+		}
+		for _, selector := range yamlPathSelector.FindAllStringSubmatch(match[2], -1) {
+			var selected []*yaml.Node
+			for _, node := range next {
+				if node.Kind != yaml.SequenceNode {
+					continue
+				}
+				items, err := selectYAMLItems(node.Content, selector[1])
+				if err != nil {
+					return nil, fmt.Errorf("invalid key path %q: %w", path, err)
+				}
+				selected = append(selected, items...)
+			}
+			next = selected
+		}
+		nodes = next
+	}
+	return nodes, nil
+}
+
+func selectYAMLItems(items []*yaml.Node, selector string) ([]*yaml.Node, error) {
+	if selector == "*" {
+		return items, nil
+	}
+	if key, value, ok := strings.Cut(selector, "="); ok {
+		var selected []*yaml.Node
+		for _, item := range items {
+			if field := mappingValue(item, key); field != nil && field.Value == value {
+				selected = append(selected, item)
+			}
+		}
+		return selected, nil
+	}
+	index, err := strconv.Atoi(selector)
+	if err != nil {
+		return nil, fmt.Errorf("unknown selector [%s]", selector)
+	}
+	if index < 0 || index >= len(items) {
+		return nil, nil
+	}
+	return []*yaml.Node{items[index]}, nil
+}
+
+// findYAMLImages returns the scalar nodes holding the image references selected by the rule
+func findYAMLImages(documents []*yaml.Node, rule yamlImageRule) ([]*yaml.Node, error) {
+	reference := imageReference(rule.ImageURL)
+	var candidates []*yaml.Node
+	for _, document := range documents {
+		switch {
+		case rule.KeyPath != "":
+			nodes, err := resolveYAMLPath(document, rule.KeyPath)
+			if err != nil {
+				return nil, err
+			}
+			candidates = append(candidates, nodes...)
+		case rule.Container != "":
+			walkYAML(document, func(node *yaml.Node) {
+				if name := mappingValue(node, "name"); name != nil && name.Value == rule.Container {
+					if image := mappingValue(node, "image"); image != nil {
+						candidates = append(candidates, image)
+					}
+				}
+			})
+		default:
+			walkYAML(document, func(node *yaml.Node) {
+				if node.Kind != yaml.MappingNode && node.Kind != yaml.SequenceNode {
+					return
+				}
+				for i, child := range node.Content {
+					// keys of mappings are never images
+					isKey := node.Kind == yaml.MappingNode && i%2 == 0
+					if !isKey && child.Kind == yaml.ScalarNode && reference.MatchString(child.Value) {
+						candidates = append(candidates, child)
+					}
+				}
+			})
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no image reference to %s found", rule)
+	}
+	for _, node := range candidates {
+		if node.Kind != yaml.ScalarNode || !reference.MatchString(node.Value) {
+			return nil, fmt.Errorf("the value at line %d is not an image reference to %s", node.Line, rule.ImageURL)
+		}
+	}
+	return candidates, nil
+}
+
+// yamlEdit replaces the value of a scalar node in the original content, adds a line after the
+// one of the node or removes that line
+type yamlEdit struct {
+	node       *yaml.Node
+	value      string
+	lineAfter  bool
+	removeLine bool
+}
+
+// applyYAMLEdits rewrites scalar values in place, so that everything else in the file
+// (comments, indentation, quoting, key order) is left exactly as it was
+func applyYAMLEdits(content string, edits []yamlEdit) (string, error) {
+	lineStarts := []int{0}
+	for i, c := range content {
+		if c == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+	type replacement struct {
+		start, end int
+		value      string
+	}
+	var replacements []replacement
+	for _, edit := range edits {
+		node := edit.node
+		if node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+			return "", fmt.Errorf("can't rewrite the block scalar at line %d", node.Line)
+		}
+		if node.Line < 1 || node.Line > len(lineStarts) {
+			return "", fmt.Errorf("invalid position of the value at line %d", node.Line)
+		}
+		// columns count characters, not bytes
+		start := lineStarts[node.Line-1]
+		for column := 1; column < node.Column && start < len(content); column++ {
+			_, size := utf8.DecodeRuneInString(content[start:])
+			start += size
+		}
+		if node.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) != 0 {
+			start++
+		}
+		end := start + len(node.Value)
+		if end > len(content) || content[start:end] != node.Value {
+			return "", fmt.Errorf("can't rewrite the escaped value at line %d", node.Line)
+		}
+		if edit.removeLine {
+			lineEnd := len(content)
+			if node.Line < len(lineStarts) {
+				lineEnd = lineStarts[node.Line]
+			}
+			replacements = append(replacements, replacement{lineStarts[node.Line-1], lineEnd, ""})
+			continue
+		}
+		if edit.lineAfter {
+			lineEnd := strings.IndexByte(content[end:], '\n')
+			if lineEnd == -1 {
+				replacements = append(replacements, replacement{len(content), len(content), "\n" + edit.value})
+			} else {
+				replacements = append(replacements, replacement{end + lineEnd, end + lineEnd, "\n" + edit.value})
+			}
+			continue
+		}
+		replacements = append(replacements, replacement{start, end, edit.value})
+	}
+	// apply from the end of the file so that earlier offsets stay valid
+	sort.Slice(replacements, func(i, j int) bool { return replacements[i].start > replacements[j].start })
+	for i, r := range replacements {
+		if i > 0 && r.start == replacements[i-1].start {
+			continue
+		}
+		content = content[:r.start] + r.value + content[r.end:]
+	}
+	return content, nil
+}
+
+// bumpYAMLImage points the image references selected by the rule at the new tag and returns
+// the updated content along with the tags they had before
+func bumpYAMLImage(content string, rule yamlImageRule, newTag string) (string, []string, error) {
+	documents, err := parseYAMLDocuments(content)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid YAML: %w", err)
+	}
+	nodes, err := findYAMLImages(documents, rule)
+	if err != nil {
+		return "", nil, err
+	}
+	reference := imageReference(rule.ImageURL)
+	var edits []yamlEdit
+	var previousTags []string
+	for _, node := range nodes {
+		previousTags = append(previousTags, reference.FindStringSubmatch(node.Value)[1])
+		edits = append(edits, yamlEdit{node: node, value: rule.ImageURL + ":" + newTag})
+	}
+	updated, err := applyYAMLEdits(content, edits)
+	if err != nil {
+		return "", nil, err
+	}
+	return updated, previousTags, nil
+}
@@ -0,0 +1,192 @@
+package gh
+
+import (
+	"reflect"
+	"testing"
+)
+
+const yamlManifest = `# staging deployment
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  labels:
+    version: "1.2.3" # not an image
+spec:
+  template:
+    spec:
+      containers:
+        - name: app
+          image: ghcr.io/org/app:1.2.3   # bumped by autodeployer
+        - name: worker
+          image: "ghcr.io/org/app:1.2.3"
+        - name: sidecar
+          image: ghcr.io/org/app-sidecar:1.2.3
+---
+apiVersion: batch/v1
+kind: Job
+spec:
+  template:
+    spec:
+      containers:
+        - name: migrate
+          image: 'ghcr.io/org/app:1.2.2@sha256:abc123'
+`
+
+func TestBumpYAMLImage(t *testing.T) {
+	testCases := []struct {
+		name         string
+		rule         yamlImageRule
+		expected     string
+		previousTags []string
+		wantErr      bool
+	}{
+		{
+			"every reference",
+			yamlImageRule{ImageURL: "ghcr.io/org/app"},
+			`# staging deployment
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  labels:
+    version: "1.2.3" # not an image
+spec:
+  template:
+    spec:
+      containers:
+        - name: app
+          image: ghcr.io/org/app:1.2.4-rc1   # bumped by autodeployer
+        - name: worker
+          image: "ghcr.io/org/app:1.2.4-rc1"
+        - name: sidecar
+          image: ghcr.io/org/app-sidecar:1.2.3
+---
+apiVersion: batch/v1
+kind: Job
+spec:
+  template:
+    spec:
+      containers:
+        - name: migrate
+          image: 'ghcr.io/org/app:1.2.4-rc1'
+`,
+			[]string{"1.2.3", "1.2.3", "1.2.2"}, false,
+		},
+		{
+			"container name",
+			yamlImageRule{ImageURL: "ghcr.io/org/app", Container: "worker"},
+			`# staging deployment
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  labels:
+    version: "1.2.3" # not an image
+spec:
+  template:
+    spec:
+      containers:
+        - name: app
+          image: ghcr.io/org/app:1.2.3   # bumped by autodeployer
+        - name: worker
+          image: "ghcr.io/org/app:1.2.4-rc1"
+        - name: sidecar
+          image: ghcr.io/org/app-sidecar:1.2.3
+---
+apiVersion: batch/v1
+kind: Job
+spec:
+  template:
+    spec:
+      containers:
+        - name: migrate
+          image: 'ghcr.io/org/app:1.2.2@sha256:abc123'
+`,
+			[]string{"1.2.3"}, false,
+		},
+		{
+			"key path",
+			yamlImageRule{ImageURL: "ghcr.io/org/app", KeyPath: "spec.template.spec.containers[0].image"},
+			`# staging deployment
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  labels:
+    version: "1.2.3" # not an image
+spec:
+  template:
+    spec:
+      containers:
+        - name: app
+          image: ghcr.io/org/app:1.2.4-rc1   # bumped by autodeployer
+        - name: worker
+          image: "ghcr.io/org/app:1.2.3"
+        - name: sidecar
+          image: ghcr.io/org/app-sidecar:1.2.3
+---
+apiVersion: batch/v1
+kind: Job
+spec:
+  template:
+    spec:
+      containers:
+        - name: migrate
+          image: 'ghcr.io/org/app:1.2.4-rc1'
+`,
+			[]string{"1.2.3", "1.2.2"}, false,
+		},
+		{"key path to another image", yamlImageRule{ImageURL: "ghcr.io/org/app", KeyPath: "spec.template.spec.containers[name=sidecar].image"}, "", nil, true},
+		{"key path to a label", yamlImageRule{ImageURL: "ghcr.io/org/app", KeyPath: "metadata.labels.version"}, "", nil, true},
+		{"unknown container", yamlImageRule{ImageURL: "ghcr.io/org/app", Container: "api"}, "", nil, true},
+		{"unknown image", yamlImageRule{ImageURL: "ghcr.io/org/api"}, "", nil, true},
+		{"invalid selector", yamlImageRule{ImageURL: "ghcr.io/org/app", KeyPath: "spec.template.spec.containers[first].image"}, "", nil, true},
+	}
+
+	for _, tc := range testCases {
+		actual, previousTags, err := bumpYAMLImage(yamlManifest, tc.rule, "1.2.4-rc1")
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s: unexpected error %v", tc.name, err)
+			continue
+		}
+		if actual != tc.expected {
+			t.Errorf("%s: expected\n%s\nbut got\n%s", tc.name, tc.expected, actual)
+		}
+		if !reflect.DeepEqual(previousTags, tc.previousTags) {
+			t.Errorf("%s: expected previous tags %v but got %v", tc.name, tc.previousTags, previousTags)
+		}
+	}
+}
+
+func TestResolveYAMLPath(t *testing.T) {
+	documents, err := parseYAMLDocuments(yamlManifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		path     string
+		expected []string
+	}{
+		{"metadata.name", []string{"app"}},
+		{"spec.template.spec.containers[*].name", []string{"app", "worker", "sidecar"}},
+		{"spec.template.spec.containers[name=worker].image", []string{"ghcr.io/org/app:1.2.3"}},
+		{"spec.template.spec.containers[5].image", nil},
+		{"spec.missing.image", nil},
+	}
+
+	for _, tc := range testCases {
+		nodes, err := resolveYAMLPath(documents[0], tc.path)
+		if err != nil {
+			t.Errorf("resolveYAMLPath(%s): unexpected error %v", tc.path, err)
+			continue
+		}
+		var actual []string
+		for _, node := range nodes {
+			actual = append(actual, node.Value)
+		}
+		if !reflect.DeepEqual(actual, tc.expected) {
+			t.Errorf("resolveYAMLPath(%s): expected %v but got %v", tc.path, tc.expected, actual)
+		}
+	}
+}
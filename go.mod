@@ -3,8 +3,10 @@ module github.com/psycho-baller/autodeployer
 go 1.22.0
 
 require (
+	github.com/BurntSushi/toml v1.4.0
 	github.com/google/go-github/v39 v39.2.0
 	gopkg.in/yaml.v2 v2.4.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
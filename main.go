@@ -2,9 +2,13 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
+	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -18,7 +22,86 @@ import (
 // Configuration struct for holding settings from config.yaml
 type Configuration struct {
 	Settings        map[string]string                       `yaml:"settings"`
-	DeploymentRepos map[string]map[string]map[string]string `yaml:"deployment_repos"`
+	DeploymentRepos map[string]map[string]RepoConfig `yaml:"deployment_repos"`
+}
+
+// RepoConfig is a source repo entry of a deployment repo. The components of a monorepo are
+// released and deployed separately, each inheriting the options of the repo.
+type RepoConfig struct {
+	Options map[string]string `yaml:",inline"`
+	// the files bumped by a deployment, each with its own image and match rule (take over
+	// staging-config-path and production-config-path when set)
+	StagingFiles    []map[string]string   `yaml:"staging-files"`
+	ProductionFiles []map[string]string   `yaml:"production-files"`
+	Components      map[string]RepoConfig `yaml:"components"`
+}
+
+// the config of a component, falling back to the options and files of the repo. The file lists
+// of the repo are only inherited when the component lists no files and has no config path of its own.
+func (c RepoConfig) component(name string) RepoConfig {
+	component := c.Components[name]
+	merged := RepoConfig{Options: map[string]string{}, StagingFiles: component.StagingFiles, ProductionFiles: component.ProductionFiles}
+	for key, value := range c.Options {
+		merged.Options[key] = value
+	}
+	for key, value := range component.Options {
+		merged.Options[key] = value
+	}
+	// a component with its own config path bumps that file rather than the files of the repo
+	if len(merged.StagingFiles) == 0 && component.Options["staging-config-path"] == "" {
+		merged.StagingFiles = c.StagingFiles
+	}
+	if len(merged.ProductionFiles) == 0 && component.Options["production-config-path"] == "" {
+		merged.ProductionFiles = c.ProductionFiles
+	}
+	return merged
+}
+
+// the manifests of an environment: the listed files, or the single `<environment>-config-path`
+func (c RepoConfig) manifests(files []map[string]string, pathOption string) []gh.ManifestFile {
+	if len(files) == 0 {
+		if c.Options[pathOption] == "" {
+			return nil
+		}
+		files = []map[string]string{{"path": c.Options[pathOption]}}
+	}
+	var manifests []gh.ManifestFile
+	for _, file := range files {
+		manifestType := file["type"]
+		if manifestType == "" {
+			manifestType = c.Options["manifest-type"]
+		}
+		parsedType, err := gh.ParseManifestType(manifestType)
+		if err != nil {
+			fmt.Printf("Error parsing the manifests of %s: %s\n", repo, err)
+			os.Exit(1)
+		}
+		manifest := gh.ManifestFile{Path: file["path"], Type: parsedType, ImageURL: file["config-image-url"], KeyPath: file["image-path"], Container: file["container-name"], Pattern: file["pattern"]}
+		if manifest.ImageURL == "" {
+			// the image of the repo, found the way the repo says unless the file says otherwise
+			manifest.ImageURL = c.Options["config-image-url"]
+			if manifest.KeyPath == "" && manifest.Container == "" {
+				manifest.KeyPath, manifest.Container = c.Options["image-path"], c.Options["container-name"]
+			}
+			if manifest.Pattern == "" {
+				manifest.Pattern = c.Options["pattern"]
+			}
+		}
+		if manifest.Type == gh.HelmManifest {
+			// the chart whose appVersion follows the tag: the one of the file, or the chart of the
+			// repo, which only follows production so that rcs never reach its appVersion
+			manifest.ChartPath = file["chart-path"]
+			if manifest.ChartPath == "" && pathOption == "production-config-path" {
+				manifest.ChartPath = c.Options["chart-path"]
+			}
+		}
+		if err := manifest.Validate(); err != nil {
+			fmt.Printf("Error parsing the manifest %s of %s: %s\n", manifest.Path, repo, err)
+			os.Exit(1)
+		}
+		manifests = append(manifests, manifest)
+	}
+	return manifests
 }
 
 var (
@@ -26,31 +109,83 @@ var (
 	workflowRetryLimit       int
 	workflowRetryWaitSeconds int
 	deploymentsRepo          string
-	deploymentYAMLPath       string
-	configImageURL           string
 	isPrerelease             bool = true
 	repo                     string
 	branch                   string
 	userDefinedOldTag        string
+	inferVersionChange       bool
+	tagDayCutoff             int
+	noCache                  bool
+	rcOwnership              string
+	oldTagFrom               string
+	command                  string
+	promoteTag               string
+	pruneOptions             gh.PruneOptions
+	dryRun                   bool
+	assumeYes                bool
 )
 
 func main() {
-	// Parse arguments
-	if len(os.Args) <= 2 {
+	// Parse flags and arguments
+	flag.BoolVar(&inferVersionChange, "infer-bump", false, "infer the version change from conventional commits since the old tag")
+	flag.IntVar(&tagDayCutoff, "days", 0, "ignore tags whose commit is older than this many days (0 disables the filter)")
+	flag.BoolVar(&noCache, "no-cache", false, "don't use the on-disk cache for GitHub API responses")
+	flag.StringVar(&rcOwnership, "rc-owner", "", "whose rc tags can be continued: mine, anyone, team or github-team (defaults to the rc_ownership setting)")
+	flag.StringVar(&oldTagFrom, "old-tag-from", "", "where the old tag comes from: tags or deployed (defaults to the old_tag_source setting)")
+	flag.IntVar(&pruneOptions.OlderThanDays, "older-than", 0, "prune: only rcs whose commit is at least this many days old")
+	flag.BoolVar(&pruneOptions.BranchGone, "branch-gone", false, "prune: rcs whose branch was merged into the default branch or deleted")
+	flag.BoolVar(&pruneOptions.Superseded, "superseded", false, "prune: rcs superseded by a final release")
+	flag.BoolVar(&pruneOptions.AllAuthors, "all-authors", false, "prune: rcs of everyone rather than only yours")
+	flag.BoolVar(&dryRun, "dry-run", false, "prune: list the rcs that would be deleted without deleting them")
+	flag.BoolVar(&assumeYes, "yes", false, "prune: don't ask for confirmation")
+	flag.Parse()
+	args := flag.Args()
+	if len(args) > 0 && (args[0] == "promote" || args[0] == "prune") {
+		command = args[0]
+		args = args[1:]
+	}
+	if command == "prune" {
+		switch {
+		case len(args) >= 1:
+			repo = args[0]
+		case os.Getenv("AD_REPO") != "":
+			repo = os.Getenv("AD_REPO")
+		default:
+			fmt.Println("Usage: go run github.com/psycho-baller/autodeployer [flags] prune <REPO_NAME>")
+			flag.PrintDefaults()
+			os.Exit(1)
+		}
+	} else if command == "promote" {
+		switch {
+		case len(args) >= 2:
+			repo = args[0]
+			promoteTag = args[1]
+		case len(args) == 1 && os.Getenv("AD_REPO") != "":
+			repo = os.Getenv("AD_REPO")
+			promoteTag = args[0]
+		default:
+			fmt.Println("Usage: go run github.com/psycho-baller/autodeployer [flags] promote <REPO_NAME> <RC_TAG>")
+			flag.PrintDefaults()
+			os.Exit(1)
+		}
+	} else if len(args) < 2 {
 		// check if they have an environment variable set
 		if os.Getenv("AD_REPO") != "" && os.Getenv("AD_BRANCH") != "" {
 			repo = os.Getenv("AD_REPO")
 			branch = os.Getenv("AD_BRANCH")
 		} else {
-			fmt.Println("Usage: go run github.com/psycho-baller/autodeployer <REPO_NAME> <BRANCH_NAME>")
+			fmt.Println("Usage: go run github.com/psycho-baller/autodeployer [flags] <REPO_NAME> <BRANCH_NAME> [OLD_TAG]")
+			fmt.Println("       go run github.com/psycho-baller/autodeployer [flags] promote <REPO_NAME> <RC_TAG>")
+			fmt.Println("       go run github.com/psycho-baller/autodeployer [flags] prune <REPO_NAME>")
+			flag.PrintDefaults()
 			os.Exit(1)
 		}
 	} else {
-		repo = os.Args[1]
-		branch = os.Args[2]
+		repo = args[0]
+		branch = args[1]
 	}
-	if len(os.Args) > 3 {
-		userDefinedOldTag = os.Args[3]
+	if command == "" && len(args) > 2 {
+		userDefinedOldTag = args[2]
 	}
 
 	token := getGHECToken()
@@ -84,19 +219,97 @@ func main() {
 	owner = config.Settings["owner"]
 	workflowRetryLimit, _ = strconv.Atoi(config.Settings["workflow_retry_limit"])
 	workflowRetryWaitSeconds, _ = strconv.Atoi(config.Settings["workflow_retry_wait_seconds"])
+	// the CLI flag takes precedence over the config setting
+	if !isFlagSet("infer-bump") {
+		inferVersionChange, _ = strconv.ParseBool(config.Settings["infer_version_change"])
+	}
+	if !isFlagSet("days") {
+		tagDayCutoff, _ = strconv.Atoi(config.Settings["tag_day_cutoff"])
+	}
+	tagCollisionPolicy, err := gh.ParseTagCollisionPolicy(config.Settings["tag_collision_policy"])
+	if err != nil {
+		fmt.Println("Error parsing config.yaml:", err)
+		os.Exit(1)
+	}
+	tagSource, err := gh.ParseTagSource(config.Settings["tag_source"])
+	if err != nil {
+		fmt.Println("Error parsing config.yaml:", err)
+		os.Exit(1)
+	}
+	if rcOwnership == "" {
+		rcOwnership = config.Settings["rc_ownership"]
+	}
+	ownershipPolicy, err := gh.ParseOwnershipPolicy(rcOwnership)
+	if err != nil {
+		fmt.Println("Error parsing rc ownership policy:", err)
+		os.Exit(1)
+	}
+	var rcTeam []string
+	for _, member := range strings.Split(config.Settings["rc_team"], ",") {
+		if member = strings.TrimSpace(member); member != "" {
+			rcTeam = append(rcTeam, member)
+		}
+	}
+	if oldTagFrom == "" {
+		oldTagFrom = config.Settings["old_tag_source"]
+	}
+	oldTagSource, err := gh.ParseOldTagSource(oldTagFrom)
+	if err != nil {
+		fmt.Println("Error parsing old tag source:", err)
+		os.Exit(1)
+	}
+	releaseNotesMode, err := gh.ParseReleaseNotesMode(config.Settings["release_notes"])
+	if err != nil {
+		fmt.Println("Error parsing config.yaml:", err)
+		os.Exit(1)
+	}
+	failedReleasePolicy, err := gh.ParseFailedReleasePolicy(config.Settings["failed_release"])
+	if err != nil {
+		fmt.Println("Error parsing config.yaml:", err)
+		os.Exit(1)
+	}
+	releaseLifecycle, err := gh.ParseReleaseLifecycle(config.Settings["release_lifecycle"])
+	if err != nil {
+		fmt.Println("Error parsing config.yaml:", err)
+		os.Exit(1)
+	}
+	tagType := config.Settings["tag_type"]
+	if tagType != "" && tagType != "lightweight" && tagType != "annotated" {
+		fmt.Printf("Error parsing config.yaml: unknown tag type %q (expected \"lightweight\" or \"annotated\")\n", tagType)
+		os.Exit(1)
+	}
+	releaseSummaryAsset, _ := strconv.ParseBool(config.Settings["release_summary_asset"])
+	changelogRCCommit, _ := strconv.ParseBool(config.Settings["changelog_rc_commit"])
 	deploymentsRepo = GetDeploymentRepo(repo, config.DeploymentRepos)
 	if deploymentsRepo == "" {
 		fmt.Printf("Deployment repo not found for %s\n", repo)
 		os.Exit(1)
 	}
-	deploymentYAMLPath = config.DeploymentRepos[deploymentsRepo][repo]["staging-config-path"]
-	configImageURL = config.DeploymentRepos[deploymentsRepo][repo]["config-image-url"]
+	repoConfig := config.DeploymentRepos[deploymentsRepo][repo]
 	// Create GitHub client
-	ghCtx := context.Background()
+	// cancel in-flight API calls when the user interrupts the run
+	ghCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
 	ts := oauth2.StaticTokenSource(
 		&oauth2.Token{AccessToken: token},
 	)
-	tc := oauth2.NewClient(ghCtx, ts)
+	var transport http.RoundTripper = http.DefaultTransport
+	var cacheDir string
+	if !noCache {
+		cacheDir = config.Settings["cache_dir"]
+		if cacheDir == "" {
+			cacheDir, err = gh.DefaultCacheDir()
+		}
+		if err == nil {
+			transport, err = gh.NewCachingTransport(cacheDir, http.DefaultTransport)
+		}
+		if err != nil {
+			fmt.Printf("Error setting up the response cache: %s\nWill not cache GitHub API responses.\n", err)
+			transport = http.DefaultTransport
+			cacheDir = ""
+		}
+	}
+	tc := &http.Client{Transport: &oauth2.Transport{Source: ts, Base: transport}}
 	client := github.NewClient(tc)
 
 	gh.Globals = gh.AppContext{
@@ -105,44 +318,302 @@ func main() {
     Branch:                   branch,
 	UserDefinedOldTag:        userDefinedOldTag,
     DeploymentsRepo:          deploymentsRepo,
-    DeploymentYAMLPath:       deploymentYAMLPath,
     WorkflowRetryLimit:       workflowRetryLimit,
     WorkflowRetryWaitSeconds: workflowRetryWaitSeconds,
-    ConfigImageURL:           configImageURL,
     IsPrerelease:             isPrerelease,
+    InferVersionChange:       inferVersionChange,
+    TagCollisionPolicy:       tagCollisionPolicy,
+    TagDayCutoff:             tagDayCutoff,
+    CacheDir:                 cacheDir,
+    TagSource:                tagSource,
+    OwnershipPolicy:          ownershipPolicy,
+    RCTeam:                   rcTeam,
+    RCGitHubTeam:             config.Settings["rc_github_team"],
+    OldTagSource:             oldTagSource,
+    ReleaseNotesMode:         releaseNotesMode,
+    ReleaseLifecycle:         releaseLifecycle,
+    FailedReleasePolicy:      failedReleasePolicy,
+    AnnotatedTags:            tagType == "annotated",
+    Tagger:                   config.Settings["tagger"],
+    ReleaseSummaryAsset:      releaseSummaryAsset,
+    ChangelogRCCommit:        changelogRCCommit,
     Ctx:                      ghCtx,
     Client:                   client,
 }
 
+	if command == "promote" {
+		promote(repoConfig)
+	} else if command == "prune" {
+		forEachComponent(repoConfig, prune)
+	} else {
+		// every component is released from the same commit, made once their changelogs are updated.
+		// Nothing is created before every component is planned, and a failing component doesn't
+		// stop the others.
+		forEachComponent(repoConfig, planRelease)
+		gh.CommitChangelogs()
+		forEachComponent(repoConfig, deploy)
+		reportComponents(repoConfig)
+	}
+	if len(failedComponents) > 0 {
+		os.Exit(1)
+	}
+}
+
+// runs a command for the repo, or once for each of its components
+func forEachComponent(repoConfig RepoConfig, run func()) {
+	if len(repoConfig.Components) == 0 {
+		applyRepoOptions("", repoConfig)
+		run()
+		return
+	}
+	components := make([]string, 0, len(repoConfig.Components))
+	for component := range repoConfig.Components {
+		components = append(components, component)
+	}
+	sort.Strings(components)
+	for _, component := range components {
+		fmt.Printf("\n=== %s/%s ===\n", repo, component)
+		applyRepoOptions(component, repoConfig.component(component))
+		run()
+	}
+}
+
+// points the run at the manifests, image and tags of the repo or of one of its components
+func applyRepoOptions(component string, repoConfig RepoConfig) {
+	options := repoConfig.Options
+	tagFormat, err := gh.ComponentTagFormat(options["tag-prefix"], options["tag-format"], options["prerelease-format"])
+	if err != nil {
+		fmt.Printf("Error parsing the tag format of %s: %s\n", repo, err)
+		os.Exit(1)
+	}
+	var releaseAssets []string
+	for _, pattern := range strings.Split(options["release-assets"], ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			releaseAssets = append(releaseAssets, pattern)
+		}
+	}
+	gh.Globals.Component = component
+	gh.Globals.TagPrefix = options["tag-prefix"]
+	gh.Globals.ComponentPath = options["path"]
+	gh.Globals.TagFormat = tagFormat
+	gh.Globals.StagingManifests = repoConfig.manifests(repoConfig.StagingFiles, "staging-config-path")
+	gh.Globals.ProductionManifests = repoConfig.manifests(repoConfig.ProductionFiles, "production-config-path")
+	gh.Globals.ConfigImageURL = options["config-image-url"]
+	gh.Globals.ChangelogPath = options["changelog-path"]
+	gh.Globals.ReleaseAssets = releaseAssets
+}
+
+// the component of a monorepo a tag belongs to, going by the tag prefixes
+func componentOfTag(repoConfig RepoConfig, tag string) (string, bool) {
+	for component := range repoConfig.Components {
+		if prefix := repoConfig.component(component).Options["tag-prefix"]; prefix != "" && strings.HasPrefix(tag, prefix) {
+			return component, true
+		}
+	}
+	return "", false
+}
+
+// the tags of the rcs of the run, by component, when it changed since its last tag
+var plannedReleases = map[string][2]string{}
+
+// the components of the run that were released, and why the others failed
+var (
+	releasedComponents = map[string]string{}
+	failedComponents   = map[string]string{}
+)
+
+// records why the current component failed, leaving the other components of the run to go on
+func failComponent(reason string) {
+	fmt.Println(reason)
+	failedComponents[gh.Globals.Component] = reason
+}
+
+// lists the outcome of each component of a monorepo run
+func reportComponents(repoConfig RepoConfig) {
+	if len(repoConfig.Components) == 0 {
+		return
+	}
+	components := make([]string, 0, len(repoConfig.Components))
+	for component := range repoConfig.Components {
+		components = append(components, component)
+	}
+	sort.Strings(components)
+	fmt.Printf("\n=== %s ===\n", repo)
+	for _, component := range components {
+		if tag, ok := releasedComponents[component]; ok {
+			fmt.Printf("%s: released %s\n", component, tag)
+		} else if reason, ok := failedComponents[component]; ok {
+			fmt.Printf("%s: failed (%s)\n", component, reason)
+		} else {
+			fmt.Printf("%s: unchanged\n", component)
+		}
+	}
+}
+
+// picks the tags of the next rc of the branch and adds its changes to the changelog
+func planRelease() {
+	if gh.Globals.HeadSHA == "" {
+		if err := gh.ResolveHeadSHA(); err != nil {
+			fmt.Printf("Error resolving the head of %s: %s\n", branch, err)
+			os.Exit(1)
+		}
+	}
+	fmt.Printf("Releasing %s at %s\n", branch, gh.Globals.HeadSHA)
 	// Get new release tag
 	oldTag, newTag, err := gh.GetOldAndNewReleaseTag("")
 	if err != nil {
-		fmt.Println("Error getting old and new release tag:", err)
-		os.Exit(1)
+		failComponent(fmt.Sprintf("Error getting old and new release tag: %s", err))
+		return
 	}
 	fmt.Println("Old release tag:", oldTag)
 	fmt.Println("New release tag:", newTag)
-	gh.CreateNewRelease(newTag)
+	changed, err := gh.HasChangesSince(oldTag)
+	if err != nil {
+		failComponent(fmt.Sprintf("Error checking for changes: %s", err))
+		return
+	}
+	if !changed {
+		fmt.Printf("Nothing changed in %s since %s, skipping %s.\n", gh.Globals.ComponentPath, oldTag, gh.Globals.Component)
+		return
+	}
+	gh.UpdateUnreleasedChangelog(oldTag, newTag)
+	plannedReleases[gh.Globals.Component] = [2]string{oldTag, newTag}
+}
+
+// creates the planned rc release and deploys it to staging
+func deploy() {
+	tags, ok := plannedReleases[gh.Globals.Component]
+	if !ok {
+		return
+	}
+	oldTag, newTag := tags[0], tags[1]
+	release := gh.CreateNewRelease(oldTag, newTag)
 	fmt.Println("Waiting for image build workflow to complete...")
-	gh.WaitForWorkflow(repo, branch)
-	newBranchRef := gh.BumpDeployment(oldTag, newTag)
-	// TODO: Add option to skip this step
-	deploymentYAMLPath = config.DeploymentRepos[deploymentsRepo][repo]["staging-config-path"]
-	var workflowName string
-	if deploymentsRepo == "apps-faculty-deploy" {
-		workflowName = "deploy.yml"
-	} else {
-		workflowName = "deploy.yaml"
+	// the image build of this tag, not the one of another component released from the same commit
+	if conclusion := gh.WaitForWorkflow(repo, newTag, gh.Globals.HeadSHA); conclusion != "success" {
+		abortRelease(release, fmt.Sprintf("the image build workflow of %s concluded with `%s`", repo, conclusion))
+		return
 	}
+	newBranchRef, deploymentSHA := gh.BumpDeployment(oldTag, newTag)
+	// TODO: Add option to skip this step
+	workflowName := getDeployWorkflowName()
 	fmt.Printf("[4/5] Triggering '%s' workflow on branch %s...\n", workflowName, newBranchRef)
 	gh.TriggerWorkflow(newBranchRef, workflowName)
 	// 3. Wait for the image build workflow to complete
 	// TODO: Add option to skip this step
-	announce(Notification, fmt.Sprintf("Deploying to %s", deploymentsRepo), fmt.Sprintf("Successfully triggered deployment workflow for %s in %s through %s", newTag, repo, deploymentsRepo))
+	announce(Notification, fmt.Sprintf("Deploying to %s", deploymentsRepo), fmt.Sprintf("Successfully triggered deployment workflow for %s (%.7s) in %s through %s", newTag, gh.Globals.HeadSHA, repo, deploymentsRepo))
 	// Waiting 5 seconds before checking the image build workflow...
 	time.Sleep(5 * time.Second)
 	fmt.Println("[5/5] Waiting for deployment workflow to complete...")
-	gh.WaitForWorkflow(deploymentsRepo, strings.Split(newBranchRef, "heads/")[1])
-	announce(Alert,fmt.Sprintf("%s branch in %s has been deployed through %s", branch, repo, deploymentsRepo),fmt.Sprintf("Old release tag: %s\nNew release tag: %s", oldTag, newTag))
+	// the branch is reused by every rc of the line, only the run of the bump commit is this deployment
+	if conclusion := gh.WaitForWorkflow(deploymentsRepo, strings.Split(newBranchRef, "heads/")[1], deploymentSHA); conclusion != "success" {
+		abortRelease(release, fmt.Sprintf("the deployment workflow of %s concluded with `%s`", deploymentsRepo, conclusion))
+		return
+	}
+	gh.FinalizeRelease(release, true, "")
+	releasedComponents[gh.Globals.Component] = newTag
+	announce(Alert,fmt.Sprintf("%s branch in %s has been deployed through %s", branch, repo, deploymentsRepo),fmt.Sprintf("Old release tag: %s\nNew release tag: %s\nCommit: %.7s", oldTag, newTag, gh.Globals.HeadSHA))
 	fmt.Println("Deployment Successful! Autodeployer terminating...")
 }
+
+// turns a staging rc into a final release and deploys it to production
+func promote(repoConfig RepoConfig) {
+	if len(repoConfig.Components) > 0 {
+		component, ok := componentOfTag(repoConfig, promoteTag)
+		if !ok {
+			fmt.Printf("%s does not belong to any component of %s\n", promoteTag, repo)
+			os.Exit(1)
+		}
+		repoConfig = repoConfig.component(component)
+		applyRepoOptions(component, repoConfig)
+	} else {
+		applyRepoOptions("", repoConfig)
+	}
+	if len(gh.Globals.ProductionManifests) == 0 {
+		fmt.Printf("No production-config-path or production-files configured for %s\n", repo)
+		os.Exit(1)
+	}
+	oldTag, err := gh.GetDeployedProductionTag()
+	if err != nil {
+		fmt.Println("Error reading the tag deployed to production:", err)
+		os.Exit(1)
+	}
+	release := gh.PromoteRelease(promoteTag)
+	finalTag := release.GetTagName()
+	fmt.Println("Old production tag:", oldTag)
+	fmt.Println("New production tag:", finalTag)
+	fmt.Println("Waiting for image build workflow to complete...")
+	// workflow runs triggered by a release are reported on the tag
+	if conclusion := gh.WaitForWorkflow(repo, finalTag, release.GetTargetCommitish()); conclusion != "success" {
+		abortRelease(release, fmt.Sprintf("the image build workflow of %s concluded with `%s`", repo, conclusion))
+		return
+	}
+	newBranchRef, deploymentSHA := gh.BumpProductionDeployment(oldTag, finalTag)
+	workflowName := repoConfig.Options["production-workflow"]
+	if workflowName == "" {
+		workflowName = getDeployWorkflowName()
+	}
+	fmt.Printf("[4/5] Triggering '%s' workflow on branch %s...\n", workflowName, newBranchRef)
+	gh.TriggerWorkflow(newBranchRef, workflowName)
+	announce(Notification, fmt.Sprintf("Deploying to production through %s", deploymentsRepo), fmt.Sprintf("Successfully triggered production deployment workflow for %s in %s through %s", finalTag, repo, deploymentsRepo))
+	// Waiting 5 seconds before checking the deployment workflow...
+	time.Sleep(5 * time.Second)
+	fmt.Println("[5/5] Waiting for production deployment workflow to complete...")
+	// a promote that is run again reuses the branch, only the run of the bump commit is this deployment
+	if conclusion := gh.WaitForWorkflow(deploymentsRepo, strings.Split(newBranchRef, "heads/")[1], deploymentSHA); conclusion != "success" {
+		abortRelease(release, fmt.Sprintf("the production deployment workflow of %s concluded with `%s`", deploymentsRepo, conclusion))
+		return
+	}
+	gh.FinalizeRelease(release, true, "")
+	announce(Alert, fmt.Sprintf("%s in %s has been promoted to production through %s", promoteTag, repo, deploymentsRepo), fmt.Sprintf("Old production tag: %s\nNew production tag: %s\nCommit: %.7s", oldTag, finalTag, release.GetTargetCommitish()))
+	fmt.Println("Promotion Successful! Autodeployer terminating...")
+}
+
+// leaves the release unpublished, tells the user why and marks the component as failed. The
+// run exits with an error once the other components are done.
+func abortRelease(release *github.RepositoryRelease, reason string) {
+	gh.FinalizeRelease(release, false, reason)
+	announce(Alert, fmt.Sprintf("Deployment of %s (%.7s) in %s failed", release.GetTagName(), release.GetTargetCommitish(), repo), reason)
+	failComponent("Deployment Failed! " + reason)
+}
+
+// deletes stale rc tags and their releases
+func prune() {
+	tags, err := gh.FindPrunableTags(pruneOptions)
+	if err != nil {
+		fmt.Println("Error finding rcs to prune:", err)
+		os.Exit(1)
+	}
+	if len(tags) == 0 {
+		fmt.Println("No rcs to prune.")
+		return
+	}
+	fmt.Printf("%d rc(s) of %s match the filters:\n", len(tags), repo)
+	gh.PrintPrunableTags(tags)
+	if dryRun {
+		fmt.Println("Dry run, nothing was deleted.")
+		return
+	}
+	if !assumeYes {
+		fmt.Print("Delete these tags and their releases? [y/N] ")
+		var answer string
+		fmt.Scanln(&answer)
+		if answer != "y" && answer != "Y" && answer != "yes" {
+			fmt.Println("Nothing was deleted.")
+			return
+		}
+	}
+	if err := gh.DeletePrunableTags(tags); err != nil {
+		fmt.Println("Error pruning rcs:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Pruned %d rc(s) of %s.\n", len(tags), repo)
+}
+
+// the workflow of the deployment repo that deploys the bumped manifests
+func getDeployWorkflowName() string {
+	if deploymentsRepo == "apps-faculty-deploy" {
+		return "deploy.yml"
+	}
+	return "deploy.yaml"
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 	"os/exec"
@@ -8,7 +9,7 @@ import (
 )
 
 // GetDeploymentRepo retrieves the deployment repo from `repoName` argument
-func GetDeploymentRepo(repoName string, deploymentRepos map[string]map[string]map[string]string) string {
+func GetDeploymentRepo(repoName string, deploymentRepos map[string]map[string]RepoConfig) string {
 	for deploymentRepo, repos := range deploymentRepos {
 		for repo := range repos {
 			if repo == repoName {
@@ -20,6 +21,17 @@ func GetDeploymentRepo(repoName string, deploymentRepos map[string]map[string]ma
 	return ""
 }
 
+// isFlagSet reports whether the flag was explicitly passed on the command line
+func isFlagSet(name string) bool {
+	found := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			found = true
+		}
+	})
+	return found
+}
+
 func getGHECToken() string {
 	cmd := exec.Command("op", "read", "op://Private/GHEC_TOKEN/token")
 	output, err := cmd.Output()